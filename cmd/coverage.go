@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/AngelVI13/used_in_tc/pkg/repo_search"
+)
+
+// coverage Reports the TCs that reach each function of the library dir
+// (pattern) and the functions that no TC reaches
+func coverage(repo *repo_search.Repo) {
+	files := repo_search.LibraryFiles(repo, args.Pattern)
+	if len(files) == 0 {
+		errorTxt := fmt.Sprintf("No files to search in library dir %s", args.Pattern)
+		fatal(errorTxt)
+	}
+
+	// Don't let functions that can't be found look like they are covered
+	if unsupported := repo_search.UnsupportedFiles(files); len(unsupported) > 0 {
+		warningTxt := fmt.Sprintf("Functions of these files are not reported (unsupported language) (%d):", len(unsupported))
+		log.Println(repo_search.WarningStyle.Render(warningTxt))
+		for _, file := range unsupported {
+			log.Printf("\t%s", repo.RelPath(file))
+		}
+	}
+
+	coverage := repo_search.Coverage(repo, files, args.Distance)
+
+	log.Println()
+	log.Println(repo_search.ImportantStyle.Render(fmt.Sprintf("Coverage of %s:", args.Pattern)))
+	for _, declaration := range coverage {
+		log.Printf(
+			"\t%d %s:%d %s [%s]",
+			len(declaration.TestCases),
+			declaration.RelPath,
+			declaration.Line,
+			repo_search.InfoStyle.Render(declaration.Name()),
+			strings.Join(declaration.TcIds(), ", "),
+		)
+	}
+
+	untested := repo_search.Untested(coverage)
+	log.Println(repo_search.WarningStyle.Render(fmt.Sprintf("Reached by no TC (%d):", len(untested))))
+	for _, declaration := range untested {
+		log.Printf("\t%s:%d %s", declaration.RelPath, declaration.Line, declaration.Name())
+	}
+
+	log.Println(repo_search.ImportantStyle.Render(repo_search.CoverageSummary(coverage)))
+
+	if args.CsvFile != "" {
+		records := repo_search.CoverageCsvRecords(coverage)
+		csvFilename := repo_search.CreateCsv(args.CsvFile, records)
+
+		infoTxt := fmt.Sprintf("Coverage Csv created successfully: %s", csvFilename)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"io"
 	"log"
 	"os"
-	"regexp"
+	"strings"
 	"time"
 
 	"github.com/AngelVI13/used_in_tc/pkg/repo_search"
@@ -16,20 +16,110 @@ import (
 //go:embed vl_template.xml
 var templateXml string
 
-var args struct {
-	UseRegex bool   `arg:"-r,--regex" default:"false" help:"Flag that enables regex search"`
-	FileType string `arg:"-t,--type" default:".py" help:"Filetypes to search (i.e. '.py')"`
+// repoArgs Options of how the repo is searched (shared by all commands)
+type repoArgs struct {
+	FileTypes []string `arg:"-t,--type,separate" help:"Filetypes to search (i.e. '.py'), repeat for multiple types [default: .py]"`
+
+	Include           []string `arg:"--include,separate" help:"Only search files matching this glob (i.e. 'lib/**'), repeat for multiple globs"`
+	Exclude           []string `arg:"--exclude,separate" help:"Skip files and directories matching this glob, repeat for multiple globs"`
+	NoDefaultExcludes bool     `arg:"--no-default-excludes" default:"false" help:"Also search .venv, site-packages, __pycache__ and .git"`
+	Gitignore         bool     `arg:"--gitignore" default:"false" help:"Skip files ignored by .gitignore files"`
 
 	// If match is not inside a testcase -> search for usage of containing method.
 	// How many levels of search to perform (trying to find a TC usage) before giving up
 	Distance int `arg:"-d,--dist" default:"6" help:"Levels of recursive search"`
 
+	After   int `arg:"-A,--after" default:"0" help:"Context lines to show after each match"`
+	Before  int `arg:"-B,--before" default:"0" help:"Context lines to show before each match"`
+	Context int `arg:"-C,--context" default:"0" help:"Context lines to show before and after each match (-A/-B take precedence)"`
+
 	LogFile string `arg:"-l,--log" default:"search.log" help:"Log filename"`
-	OutFile string `arg:"-o,--out" default:"search_tc.xml" help:"Output xml filename"`
 	WiFile  string `arg:"-w,--wi" default:"" help:"Exported XML file from polarion containing all TCA work item info."`
+}
+
+func (a *repoArgs) filter() repo_search.FileFilter {
+	if len(a.FileTypes) == 0 {
+		a.FileTypes = []string{".py"}
+	}
+
+	filter := repo_search.FileFilter{
+		FileTypes:    a.FileTypes,
+		Include:      a.Include,
+		Exclude:      a.Exclude,
+		UseGitignore: a.Gitignore,
+	}
+	if !a.NoDefaultExcludes {
+		filter.Exclude = append(filter.Exclude, repo_search.DefaultExcludes...)
+	}
+	return filter
+}
 
-	Pattern string `arg:"positional,required" help:"Pattern to search for"`
-	Dir     string `arg:"positional,required" help:"Directory to search in"`
+func (a *repoArgs) matchContext() repo_search.MatchContext {
+	context := repo_search.MatchContext{Before: a.Context, After: a.Context}
+	if a.Before > 0 {
+		context.Before = a.Before
+	}
+	if a.After > 0 {
+		context.After = a.After
+	}
+	return context
+}
+
+func (a *repoArgs) workItems() repo_search.WorkItems {
+	if a.WiFile == "" {
+		return nil
+	}
+	return repo_search.GetWorkItemsFromPolarionExport(a.WiFile)
+}
+
+// searchArgs Arguments of the `search` command
+type searchArgs struct {
+	UseRegex bool   `arg:"-r,--regex" default:"false" help:"Flag that enables regex search (same as --match regex)"`
+	Match    string `arg:"-m,--match" default:"literal" help:"How to match the pattern: literal, regex, icase (case-insensitive), word (whole word), ident (not part of a longer identifier) or struct (python code pattern with $name and $... placeholders)"`
+
+	repoArgs
+
+	Rev        string `arg:"--rev" default:"" help:"Search the files as they are at this git revision (commit, branch or tag) instead of the working tree"`
+	CompareRev string `arg:"--compare" default:"" help:"Also search this git revision and report the TCs that are newly reached, no longer reached or reached through a different chain"`
+	Stdin      bool   `arg:"--stdin" default:"false" help:"Only search the files listed on stdin (one per line, i.e. from 'git ls-files')"`
+	Coverage   bool   `arg:"--coverage" default:"false" help:"Treat the pattern as a library directory (i.e. 'lib') and report which TCs reach each function declared in it"`
+	Reverse    bool   `arg:"--reverse" default:"false" help:"Treat the pattern as TC IDs or TC script paths (comma separated) and list the functions they call up to --dist calls deep"`
+
+	Watch         bool          `arg:"--watch" default:"false" help:"Keep running and search again when searched files change (outputs are rewritten when the reached TCs change). Not for zip/tar snapshots"`
+	WatchInterval time.Duration `arg:"--interval" default:"1s" help:"How often to check for changed files in watch mode"`
+
+	OutFile string `arg:"-o,--out" default:"search_tc.xml" help:"Output xml filename"`
+
+	CsvFile      string `arg:"--csv" default:"" help:"Output csv filename with one row per TC (use .tsv extension for tab separated values)"`
+	MatchCsvFile string `arg:"--matches-csv" default:"" help:"Output csv filename with one row per search result (use .tsv extension for tab separated values)"`
+
+	SaveBaseline string `arg:"--save-baseline" default:"" help:"Save the found TCs to this file (json) to compare later runs against it"`
+	Baseline     string `arg:"--baseline" default:"" help:"Compare the found TCs with a file saved by --save-baseline (added/removed TCs, status and estimate changes)"`
+	AddedOutFile string `arg:"--added-out" default:"" help:"With --baseline, also write an xml with only the TCs that are not in the baseline"`
+	MergeFile    string `arg:"--merge" default:"" help:"Existing ta-tool-export xml to add the found TCs to (TCs already in it are skipped). The merged xml is written to --out"`
+
+	Interactive bool `arg:"-i,--interactive" default:"false" help:"Review and select found TCs in a terminal UI before writing outputs"`
+
+	Pattern string   `arg:"positional,required" help:"Pattern to search for"`
+	Dirs    []string `arg:"positional,required" help:"Directories (or zip/tar snapshots of them) to search in (i.e. the test automation repo and shared library checkouts)"`
+}
+
+// args Arguments of the search (shared by the search modes)
+var args searchArgs
+
+// commands Only one of them is set. Patterns are given after the command so
+// that they can't be mistaken for one.
+type commands struct {
+	Search *searchArgs `arg:"subcommand:search" help:"Search for the pattern and the TCs that reach it"`
+	Serve  *serveArgs  `arg:"subcommand:serve" help:"Serve searches of the dirs over HTTP"`
+	Lsp    *lspArgs    `arg:"subcommand:lsp" help:"Language server (over stdio) that shows the TCs reaching a function on hover and as code lens"`
+}
+
+func (commands) Description() string {
+	return "Finds the TCs that use a pattern (directly or through the functions it is used in).\n" +
+		"The serve command answers:\n" +
+		"  GET /search?pattern=<pattern>&match=<kind>&dist=<degrees>&format=<json|xml>\n" +
+		"  GET /symbol?file=<file>&name=<method>&class=<class>&dist=<degrees>&format=<json|xml>\n"
 }
 
 func setupLogger(filename string) {
@@ -44,53 +134,298 @@ func setupLogger(filename string) {
 	log.SetOutput(mw)
 }
 
+// openRoots Opens the searched dirs. If rev is not empty their files are read
+// from that git revision.
+func openRoots(dirs []string, rev string) []repo_search.Root {
+	roots := []repo_search.Root{}
+	for _, dir := range dirs {
+		var (
+			root repo_search.Root
+			err  error
+		)
+		if rev != "" {
+			root, err = repo_search.GitRoot(dir, rev)
+		} else {
+			root, err = repo_search.OpenRoot(dir)
+		}
+		if err != nil {
+			// fatal only closes what is already registered -> close the
+			// roots opened so far
+			closeRoots(roots)
+			errorTxt := fmt.Sprintf("Couldn't open %s: %v", dir, err)
+			fatal(errorTxt)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+func closeRoots(roots []repo_search.Root) {
+	for _, root := range roots {
+		root.Close()
+	}
+}
+
+// cleanups Run by fatal before exiting (log.Fatal skips deferred calls)
+var cleanups []func()
+
+// fatal Logs the error and exits after running the cleanups (i.e. closing the
+// repo and its cat-file processes)
+func fatal(errorTxt string) {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+	log.Fatal(repo_search.ErrorStyle.Render(errorTxt))
+}
+
+// searchTcs Runs the recursive search for the pattern in repo. The search
+// results are recorded (in repo.Recorder) if they are exported and what the
+// search depends on is traced (in repo.Trace) if it is repeated on changes.
+func searchTcs(repo *repo_search.Repo, matcher repo_search.Matcher) repo_search.TestCasesMap {
+	if args.MatchCsvFile != "" {
+		repo.Recorder = &repo_search.ResultRecorder{}
+	}
+	if args.Watch {
+		repo.Trace = &repo_search.SearchTrace{}
+	}
+	return repo_search.SearchForUsagesInTc(repo, repo_search.SearchedSet{}, matcher, args.Distance)
+}
+
 func main() {
-	arg.MustParse(&args)
+	var cmd commands
+	parser := arg.MustParse(&cmd)
+	switch {
+	case cmd.Serve != nil:
+		serve(cmd.Serve)
+		return
+	case cmd.Lsp != nil:
+		lsp(cmd.Lsp)
+		return
+	case cmd.Search == nil:
+		parser.Fail("missing command (search, serve or lsp)")
+	}
+	args = *cmd.Search
+	filter := args.filter()
 
 	setupLogger(args.LogFile)
 
+	if args.Watch && (args.Interactive || args.Rev != "") {
+		log.Fatal(repo_search.ErrorStyle.Render("--watch can't be combined with --interactive or --rev"))
+	}
+	if (args.Reverse || args.Coverage) && (args.Watch || args.Interactive || args.CompareRev != "") {
+		log.Fatal(repo_search.ErrorStyle.Render("--reverse and --coverage can't be combined with --watch, --interactive or --compare"))
+	}
+	if args.AddedOutFile != "" && args.Baseline == "" {
+		log.Fatal(repo_search.ErrorStyle.Render("--added-out requires --baseline"))
+	}
+
 	// pattern := `\.outputHeater\.set_disconnected`
-	var (
-		searchPatternRegex *regexp.Regexp
-		err                error
-	)
 	if args.UseRegex {
-		searchPatternRegex, err = regexp.Compile(args.Pattern)
-		if err != nil {
-			errorTxt := fmt.Sprintf("Couldn't compile search pattern %s: %v", args.Pattern, err)
-			log.Fatalf(repo_search.ErrorStyle.Render(errorTxt))
-		}
+		args.Match = "regex"
+	}
+	matcher, err := repo_search.NewMatcher(args.Match, args.Pattern)
+	if err != nil {
+		errorTxt := fmt.Sprintf("Couldn't create matcher for search pattern %s: %v", args.Pattern, err)
+		log.Fatalf(repo_search.ErrorStyle.Render(errorTxt))
 	}
 
 	start := time.Now()
 
-	searchTxt := args.Pattern
-	if args.UseRegex {
-		searchTxt = searchPatternRegex.String()
-	}
+	searchTxt := matcher.String()
 
+	mode := fmt.Sprintf("M(%s)", args.Match)
+	if args.Reverse {
+		mode = "Reverse"
+	} else if args.Coverage {
+		mode = "Coverage"
+	}
 	log.Printf(repo_search.ImportantStyle.Render(fmt.Sprintf(
-		"Searching for: R(%v) |%s| (%s) %s D(%d)",
-		args.UseRegex,
+		"Searching for: %s |%s| %v %s D(%d)",
+		mode,
 		searchTxt,
-		args.FileType,
-		args.Dir,
+		args.FileTypes,
+		args.Dirs,
 		args.Distance,
 	)))
+	if args.Rev != "" {
+		log.Println(repo_search.InfoStyle.Render(fmt.Sprintf("Reading files at revision %s", args.Rev)))
+	}
 
-	var testCases repo_search.TestCasesMap
-	// Initialize global var holding already searched data
-	repo_search.AlreadySearched = map[string]bool{}
+	var listedFiles []string
+	if args.Stdin {
+		listedFiles, err = repo_search.ReadFileList(os.Stdin)
+		if err != nil {
+			errorTxt := fmt.Sprintf("Couldn't read file list from stdin: %v", err)
+			log.Fatal(repo_search.ErrorStyle.Render(errorTxt))
+		}
+	}
 
-	if args.UseRegex {
-		testCases = repo_search.SearchForUsagesInTc(args.Dir, args.FileType, searchPatternRegex, args.Distance)
+	roots := openRoots(args.Dirs, args.Rev)
+	for _, root := range roots {
+		if args.Watch && root.IsSnapshot() {
+			closeRoots(roots)
+			errorTxt := fmt.Sprintf("--watch can't be used with snapshot %s (its files never change)", root.Dir)
+			log.Fatal(repo_search.ErrorStyle.Render(errorTxt))
+		}
+	}
+
+	repo := repo_search.NewRepo(roots, filter, listedFiles)
+	defer repo.Close()
+	cleanups = append(cleanups, repo.Close)
+	repo.Context = args.matchContext()
+
+	if args.Reverse || args.Coverage {
+		if args.Reverse {
+			reverse(repo)
+		} else {
+			coverage(repo)
+		}
+		log.Println("Elapsed time", time.Since(start).Seconds())
+		return
+	}
+
+	testCases := searchTcs(repo, matcher)
+
+	var comparison *repo_search.TcComparison
+	if args.CompareRev != "" {
+		infoTxt := fmt.Sprintf("Searching revision %s for comparison", args.CompareRev)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+
+		oldRepo := repo_search.NewRepo(openRoots(args.Dirs, args.CompareRev), filter, listedFiles)
+		oldRepo.Context = repo.Context
+		oldTestCases := searchTcs(oldRepo, matcher)
+		// Not needed anymore, its roots would otherwise stay open while watching
+		oldRepo.Close()
+		result := repo_search.CompareTestCases(oldTestCases, testCases)
+		comparison = &result
+	}
+
+	if args.Interactive {
+		var (
+			confirmed bool
+			err       error
+		)
+		testCases, confirmed, err = repo_search.ReviewTestCases(testCases)
+		if err != nil {
+			errorTxt := fmt.Sprintf("Couldn't run interactive review: %v", err)
+			fatal(errorTxt)
+		}
+		if !confirmed {
+			log.Println(repo_search.WarningStyle.Render("Review cancelled. No files written."))
+			return
+		}
+	}
+
+	workItems := args.workItems()
+
+	outputs := writeOutputs(testCases, repo.Recorder.Results(), workItems, searchTxt)
+	logResults(testCases, searchTxt)
+
+	if comparison != nil {
+		newRev := args.Rev
+		if newRev == "" {
+			newRev = "working tree"
+		}
+		log.Println(comparison.Report(args.CompareRev, newRev))
+	}
+
+	addedFilename := ""
+	if args.Baseline != "" {
+		addedFilename = compareBaseline(testCases, workItems, searchTxt)
+	}
+
+	logOutputs(outputs)
+
+	if addedFilename != "" {
+		infoTxt := fmt.Sprintf("Added TCs Xml created successfully: %s", addedFilename)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	}
+
+	if args.SaveBaseline != "" {
+		baseline := repo_search.NewBaseline(searchTxt, testCases, workItems)
+		if err := repo_search.SaveBaseline(args.SaveBaseline, baseline); err != nil {
+			errorTxt := fmt.Sprintf("Couldn't save baseline %s: %v", args.SaveBaseline, err)
+			fatal(errorTxt)
+		}
+		infoTxt := fmt.Sprintf("Baseline saved successfully: %s", args.SaveBaseline)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	}
+	log.Println("Elapsed time", time.Since(start).Seconds())
+
+	if args.Watch {
+		watch(repo, matcher, testCases, workItems)
+	}
+}
+
+// compareBaseline Reports the differences of testCases to the baseline file.
+// Returns the name of the xml with only the added TCs ("" if not written).
+func compareBaseline(testCases repo_search.TestCasesMap, workItems repo_search.WorkItems, searchTxt string) string {
+	baseline, err := repo_search.LoadBaseline(args.Baseline)
+	if err != nil {
+		errorTxt := fmt.Sprintf("Couldn't load baseline: %v", err)
+		fatal(errorTxt)
+	}
+	if baseline.Pattern != searchTxt {
+		warningTxt := fmt.Sprintf("Baseline was saved for a different pattern: %s", baseline.Pattern)
+		log.Println(repo_search.WarningStyle.Render(warningTxt))
+	}
+
+	current := repo_search.NewBaseline(searchTxt, testCases, workItems)
+	comparison := repo_search.CompareBaseline(baseline, current)
+	log.Println(comparison.Report(baseline.Created))
+
+	if args.AddedOutFile == "" {
+		return ""
+	}
+	added := repo_search.FilterTestCases(testCases, comparison.AddedIds())
+	protocolTxt := repo_search.CreateProtocolXml(added, workItems)
+	return repo_search.CreateXml(templateXml, args.AddedOutFile, searchTxt, protocolTxt)
+}
+
+// outputFiles Names of the written output files ("" if not written)
+type outputFiles struct {
+	xml      string
+	csv      string
+	matchCsv string
+	// merge TCs added to/already in the export of --merge (nil if not merged)
+	merge *repo_search.VlMerge
+}
+
+func writeOutputs(
+	testCases repo_search.TestCasesMap,
+	results []repo_search.RecordedResult,
+	workItems repo_search.WorkItems,
+	searchTxt string,
+) outputFiles {
+	outputs := outputFiles{}
+
+	if args.MergeFile != "" {
+		export, err := repo_search.LoadVlExport(args.MergeFile)
+		if err != nil {
+			errorTxt := fmt.Sprintf("Couldn't read export to merge into: %v", err)
+			fatal(errorTxt)
+		}
+		merge := export.Merge(searchTxt, testCases, workItems)
+		outputs.merge = &merge
+		outputs.xml = repo_search.WriteXml(args.OutFile, merge.Text)
 	} else {
-		testCases = repo_search.SearchForUsagesInTc(args.Dir, args.FileType, args.Pattern, args.Distance)
+		protocolTxt := repo_search.CreateProtocolXml(testCases, workItems)
+		outputs.xml = repo_search.CreateXml(templateXml, args.OutFile, searchTxt, protocolTxt)
 	}
 
-	protocolTxt := repo_search.CreateProtocolXml(testCases, args.WiFile)
-	outFilename := repo_search.CreateXml(templateXml, args.OutFile, searchTxt, protocolTxt)
+	if args.CsvFile != "" {
+		records := repo_search.TcCsvRecords(testCases, workItems)
+		outputs.csv = repo_search.CreateCsv(args.CsvFile, records)
+	}
 
+	if args.MatchCsvFile != "" {
+		records := repo_search.MatchCsvRecords(results)
+		outputs.matchCsv = repo_search.CreateCsv(args.MatchCsvFile, records)
+	}
+	return outputs
+}
+
+func logResults(testCases repo_search.TestCasesMap, searchTxt string) {
 	log.Println()
 
 	searchInfoTxt := fmt.Sprintf("Search results for: %s", searchTxt)
@@ -99,9 +434,74 @@ func main() {
 	infoTxt := fmt.Sprintf("Used in test cases (%d):", len(testCases))
 	log.Println(repo_search.InfoStyle.Render(infoTxt))
 	log.Println(repo_search.InfoStyle.Render(testCases.String()))
+}
 
-	infoTxt = fmt.Sprintf("TC Xml created successfully: %s", outFilename)
+func logOutputs(outputs outputFiles) {
+	if outputs.merge != nil {
+		infoTxt := fmt.Sprintf("Added to %s (%d):", args.MergeFile, len(outputs.merge.Added))
+		log.Println(repo_search.InfoStyle.Render(infoTxt))
+		log.Println(repo_search.InfoStyle.Render(strings.Join(outputs.merge.Added, ", ")))
+
+		infoTxt = fmt.Sprintf("Already planned (%d):", len(outputs.merge.Planned))
+		log.Println(repo_search.InfoStyle.Render(infoTxt))
+		log.Println(repo_search.InfoStyle.Render(strings.Join(outputs.merge.Planned, ", ")))
+	}
+
+	infoTxt := fmt.Sprintf("TC Xml created successfully: %s", outputs.xml)
 	log.Println(repo_search.ImportantStyle.Render(infoTxt))
 
-	log.Println("Elapsed time", time.Since(start).Seconds())
+	if outputs.csv != "" {
+		infoTxt = fmt.Sprintf("TC Csv created successfully: %s", outputs.csv)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	}
+
+	if outputs.matchCsv != "" {
+		infoTxt = fmt.Sprintf("Matches Csv created successfully: %s", outputs.matchCsv)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	}
+}
+
+// watch Polls the searched files for changes and searches again after every
+// change that can affect the found TCs. Only changed files are read and
+// searched again, results of the other files are reused. Runs until the
+// process is stopped.
+func watch(repo *repo_search.Repo, matcher repo_search.Matcher, testCases repo_search.TestCasesMap, workItems repo_search.WorkItems) {
+	infoTxt := fmt.Sprintf("Watching for changes every %v (ctrl+c to stop)", args.WatchInterval)
+	log.Println(repo_search.InfoStyle.Render(infoTxt))
+
+	for {
+		time.Sleep(args.WatchInterval)
+
+		changed := repo.Refresh()
+		if len(changed) == 0 {
+			continue
+		}
+
+		start := time.Now()
+		log.Println(repo_search.ImportantStyle.Render(fmt.Sprintf("Changed files (%d):", len(changed))))
+		for _, file := range changed {
+			log.Printf("\t%s", repo.RelPath(file))
+		}
+
+		// The trace of the last search stays valid as long as nothing it
+		// depends on changed
+		if affected := repo.Trace.Affected(repo, changed); len(affected) == 0 {
+			log.Println(repo_search.InfoStyle.Render("Changed files don't affect the search"))
+			continue
+		}
+
+		newTestCases := searchTcs(repo, matcher)
+		comparison := repo_search.CompareTestCases(testCases, newTestCases)
+		if comparison.IsEmpty() {
+			log.Println(repo_search.InfoStyle.Render("Reached TCs didn't change"))
+			continue
+		}
+		testCases = newTestCases
+
+		outputs := writeOutputs(testCases, repo.Recorder.Results(), workItems, matcher.String())
+		logResults(testCases, matcher.String())
+		log.Println(comparison.Report("previous search", "current search"))
+		logOutputs(outputs)
+		log.Println("Elapsed time", time.Since(start).Seconds())
+	}
 }
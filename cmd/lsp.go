@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/AngelVI13/used_in_tc/pkg/repo_search"
+)
+
+type lspArgs struct {
+	repoArgs
+
+	Dirs []string `arg:"positional,required" help:"Directories to search in (i.e. the test automation repo and shared library checkouts)"`
+}
+
+// lsp Runs the `lsp` command
+func lsp(lspArgs *lspArgs) {
+	filter := lspArgs.filter()
+
+	// Stdout belongs to the protocol -> search output only goes to the log file
+	os.Remove(lspArgs.LogFile)
+	logFile, err := os.OpenFile(lspArgs.LogFile, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		panic(err)
+	}
+	log.SetOutput(logFile)
+
+	// Editors send absolute paths
+	dirs := []string{}
+	for _, dir := range lspArgs.Dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			errorTxt := fmt.Sprintf("Couldn't get absolute path of %s: %v", dir, err)
+			log.Fatal(repo_search.ErrorStyle.Render(errorTxt))
+		}
+		dirs = append(dirs, absDir)
+	}
+
+	repo := repo_search.NewRepo(openRoots(dirs, ""), filter, nil)
+	defer repo.Close()
+	cleanups = append(cleanups, repo.Close)
+	repo.Context = lspArgs.matchContext()
+	repo.Output = logFile
+
+	server := repo_search.NewLanguageServer(repo, lspArgs.Distance, os.Stdin, os.Stdout)
+	if err := server.Run(); err != nil {
+		errorTxt := fmt.Sprintf("Language server stopped: %v", err)
+		fatal(errorTxt)
+	}
+}
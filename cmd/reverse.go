@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/AngelVI13/used_in_tc/pkg/repo_search"
+)
+
+// reverse Lists the functions reached by each TC of the pattern and how much
+// the TCs overlap
+func reverse(repo *repo_search.Repo) {
+	index := repo_search.NewReachIndex(repo)
+
+	tcs := []string{}
+	reached := map[string][]repo_search.ReachedDeclaration{}
+	for _, tc := range strings.Split(args.Pattern, ",") {
+		tc = strings.TrimSpace(tc)
+		if tc == "" {
+			continue
+		}
+
+		location, ok := repo_search.FindTc(repo, tc)
+		if !ok {
+			errorTxt := fmt.Sprintf("Couldn't find TC %s", tc)
+			fatal(errorTxt)
+		}
+
+		tcs = append(tcs, tc)
+		reached[tc] = index.Reached(location, args.Distance)
+
+		log.Println()
+		infoTxt := fmt.Sprintf("%s (%s) reaches (%d):", tc, repo.RelPath(location.File), len(reached[tc]))
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+		for _, declaration := range reached[tc] {
+			log.Printf(
+				"\t%d %s:%d %s %s",
+				declaration.Depth,
+				declaration.RelPath,
+				declaration.Line,
+				repo_search.InfoStyle.Render(declaration.Name()),
+				strings.Join(declaration.Chain, " -> "),
+			)
+		}
+	}
+
+	if len(tcs) > 1 {
+		log.Println()
+		log.Println(repo_search.ImportantStyle.Render("Overlap:"))
+	}
+	for i, a := range tcs {
+		for _, b := range tcs[i+1:] {
+			shared := len(repo_search.SharedDeclarations(reached[a], reached[b]))
+			union := len(reached[a]) + len(reached[b]) - shared
+
+			percent := 0
+			if union > 0 {
+				percent = shared * 100 / union
+			}
+			log.Printf("\t%s & %s: %d shared (%d%% of all reached)", a, b, shared, percent)
+		}
+	}
+
+	if args.CsvFile != "" {
+		records := repo_search.ReachedCsvRecords(tcs, reached)
+		csvFilename := repo_search.CreateCsv(args.CsvFile, records)
+
+		infoTxt := fmt.Sprintf("Reached Csv created successfully: %s", csvFilename)
+		log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/AngelVI13/used_in_tc/pkg/repo_search"
+)
+
+type serveArgs struct {
+	repoArgs
+
+	Addr     string        `arg:"--addr" default:"localhost:8080" help:"Address to listen on"`
+	Interval time.Duration `arg:"--interval" default:"2s" help:"How often to check for changed files (0 disables it)"`
+
+	Dirs []string `arg:"positional,required" help:"Directories (or zip/tar snapshots of them) to search in"`
+}
+
+// serve Runs the `serve` command
+func serve(serveArgs *serveArgs) {
+	filter := serveArgs.filter()
+
+	setupLogger(serveArgs.LogFile)
+
+	log.Printf(repo_search.ImportantStyle.Render(fmt.Sprintf(
+		"Indexing: %v %s D(%d)", serveArgs.FileTypes, serveArgs.Dirs, serveArgs.Distance,
+	)))
+
+	repo := repo_search.NewRepo(openRoots(serveArgs.Dirs, ""), filter, nil)
+	defer repo.Close()
+	cleanups = append(cleanups, repo.Close)
+	repo.Context = serveArgs.matchContext()
+
+	server := &repo_search.Server{
+		Repo:      repo,
+		Template:  templateXml,
+		WorkItems: serveArgs.workItems(),
+		Degrees:   serveArgs.Distance,
+	}
+	if serveArgs.Interval > 0 {
+		go server.RefreshEvery(serveArgs.Interval)
+	}
+
+	infoTxt := fmt.Sprintf("Listening on http://%s", serveArgs.Addr)
+	log.Println(repo_search.ImportantStyle.Render(infoTxt))
+	if err := http.ListenAndServe(serveArgs.Addr, server.Handler()); err != nil {
+		errorTxt := fmt.Sprintf("Couldn't serve on %s: %v", serveArgs.Addr, err)
+		fatal(errorTxt)
+	}
+}
@@ -0,0 +1,173 @@
+package repo_search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Baseline Saved results of a run that later runs are compared against
+type Baseline struct {
+	Pattern   string   `json:"pattern"`
+	Created   string   `json:"created"`
+	TestCases []TcJson `json:"testCases"`
+}
+
+// NewBaseline Baseline of the TCs found for pattern at the current time
+func NewBaseline(pattern string, testCases TestCasesMap, workItems WorkItems) Baseline {
+	return Baseline{
+		Pattern:   pattern,
+		Created:   time.Now().Format(time.RFC3339),
+		TestCases: TcJsonRecords(testCases, workItems),
+	}
+}
+
+// SaveBaseline Writes the baseline to path (json)
+func SaveBaseline(path string, baseline Baseline) error {
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0666)
+}
+
+// LoadBaseline Reads a baseline written by SaveBaseline
+func LoadBaseline(path string) (Baseline, error) {
+	baseline := Baseline{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return baseline, err
+	}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return baseline, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// StatusChange Approval status of a TC that changed since the baseline
+type StatusChange struct {
+	Id  string
+	Old string
+	New string
+}
+
+// DurationChange Estimate of a TC that changed since the baseline
+type DurationChange struct {
+	Id     string
+	Old    string
+	New    string
+	OldSec int
+	NewSec int
+}
+
+// BaselineComparison Difference between a baseline and a new run
+type BaselineComparison struct {
+	Added           []TcJson
+	Removed         []TcJson
+	StatusChanged   []StatusChange
+	DurationChanged []DurationChange
+}
+
+// CompareBaseline Returns the TCs added to/removed from newBaseline and the
+// changes of TCs that are in both
+func CompareBaseline(oldBaseline, newBaseline Baseline) BaselineComparison {
+	comparison := BaselineComparison{}
+
+	oldTcs := map[string]TcJson{}
+	for _, tc := range oldBaseline.TestCases {
+		oldTcs[tc.Id] = tc
+	}
+	newTcs := map[string]TcJson{}
+	for _, tc := range newBaseline.TestCases {
+		newTcs[tc.Id] = tc
+	}
+
+	for _, tc := range newBaseline.TestCases {
+		oldTc, ok := oldTcs[tc.Id]
+		if !ok {
+			comparison.Added = append(comparison.Added, tc)
+			continue
+		}
+
+		// Status is only known if both runs had work items
+		if oldTc.Status != "" && tc.Status != "" && oldTc.Status != tc.Status {
+			comparison.StatusChanged = append(comparison.StatusChanged, StatusChange{tc.Id, oldTc.Status, tc.Status})
+		}
+		if oldTc.Seconds != tc.Seconds {
+			comparison.DurationChanged = append(comparison.DurationChanged, DurationChange{
+				Id:     tc.Id,
+				Old:    oldTc.Estimate,
+				New:    tc.Estimate,
+				OldSec: oldTc.Seconds,
+				NewSec: tc.Seconds,
+			})
+		}
+	}
+
+	for _, tc := range oldBaseline.TestCases {
+		if _, ok := newTcs[tc.Id]; !ok {
+			comparison.Removed = append(comparison.Removed, tc)
+		}
+	}
+
+	return comparison
+}
+
+func (c BaselineComparison) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.StatusChanged) == 0 && len(c.DurationChanged) == 0
+}
+
+// AddedIds Returns the IDs of the TCs that are not in the baseline
+func (c BaselineComparison) AddedIds() []string {
+	ids := []string{}
+	for _, tc := range c.Added {
+		ids = append(ids, tc.Id)
+	}
+	return ids
+}
+
+// Report Styled summary of the differences to the baseline created at baselineTime
+func (c BaselineComparison) Report(baselineTime string) string {
+	lines := []string{
+		ImportantStyle.Render(fmt.Sprintf("Comparison with baseline from %s:", baselineTime)),
+	}
+	if c.IsEmpty() {
+		lines = append(lines, InfoStyle.Render("Same TCs with the same status and estimates"))
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, InfoStyle.Render(fmt.Sprintf("Added TCs (%d):", len(c.Added))))
+	for _, tc := range c.Added {
+		lines = append(lines, fmt.Sprintf("\t%s (%s): %s", tc.Id, tc.Estimate, formatChain(tc.Chain)))
+	}
+
+	lines = append(lines, WarningStyle.Render(fmt.Sprintf("Removed TCs (%d):", len(c.Removed))))
+	for _, tc := range c.Removed {
+		lines = append(lines, fmt.Sprintf("\t%s (%s): %s", tc.Id, tc.Estimate, formatChain(tc.Chain)))
+	}
+
+	lines = append(lines, InfoStyle.Render(fmt.Sprintf("TCs with changed status (%d):", len(c.StatusChanged))))
+	for _, change := range c.StatusChanged {
+		lines = append(lines, fmt.Sprintf("\t%s: %s -> %s", change.Id, change.Old, change.New))
+	}
+
+	lines = append(lines, InfoStyle.Render(fmt.Sprintf("TCs with changed estimate (%d):", len(c.DurationChanged))))
+	for _, change := range c.DurationChanged {
+		lines = append(lines, fmt.Sprintf("\t%s: %s -> %s (%+ds)", change.Id, change.Old, change.New, change.NewSec-change.OldSec))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// FilterTestCases Returns only the TCs with the given IDs
+func FilterTestCases(testCases TestCasesMap, ids []string) TestCasesMap {
+	filtered := TestCasesMap{}
+	for _, id := range ids {
+		if tc, ok := testCases[id]; ok {
+			filtered[id] = tc
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,92 @@
+package repo_search
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareBaseline(t *testing.T) {
+	tc := func(id, status string, seconds int) TcJson {
+		return TcJson{Id: id, Status: status, Estimate: fmt.Sprintf("%ds", seconds), Seconds: seconds}
+	}
+
+	tests := []struct {
+		name string
+		old  []TcJson
+		new  []TcJson
+		want BaselineComparison
+	}{
+		{
+			name: "unchanged",
+			old:  []TcJson{tc("4AP2-1001", "approved", 60)},
+			new:  []TcJson{tc("4AP2-1001", "approved", 60)},
+			want: BaselineComparison{},
+		},
+		{
+			name: "added",
+			old:  []TcJson{tc("4AP2-1001", "", 60)},
+			new:  []TcJson{tc("4AP2-1001", "", 60), tc("4AP2-1002", "", 30)},
+			want: BaselineComparison{Added: []TcJson{tc("4AP2-1002", "", 30)}},
+		},
+		{
+			name: "removed",
+			old:  []TcJson{tc("4AP2-1001", "", 60), tc("4AP2-1002", "", 30)},
+			new:  []TcJson{tc("4AP2-1002", "", 30)},
+			want: BaselineComparison{Removed: []TcJson{tc("4AP2-1001", "", 60)}},
+		},
+		{
+			name: "status",
+			old:  []TcJson{tc("4AP2-1001", "draft", 60)},
+			new:  []TcJson{tc("4AP2-1001", "approved", 60)},
+			want: BaselineComparison{StatusChanged: []StatusChange{{"4AP2-1001", "draft", "approved"}}},
+		},
+		{
+			name: "status without work items",
+			old:  []TcJson{tc("4AP2-1001", "draft", 60)},
+			new:  []TcJson{tc("4AP2-1001", "", 60)},
+			want: BaselineComparison{},
+		},
+		{
+			name: "duration",
+			old:  []TcJson{tc("4AP2-1001", "", 60)},
+			new:  []TcJson{tc("4AP2-1001", "", 90)},
+			want: BaselineComparison{DurationChanged: []DurationChange{{
+				Id: "4AP2-1001", Old: fmt.Sprintf("%ds", 60), New: fmt.Sprintf("%ds", 90), OldSec: 60, NewSec: 90,
+			}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := CompareBaseline(Baseline{TestCases: test.old}, Baseline{TestCases: test.new})
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if got.IsEmpty() != reflect.DeepEqual(test.want, BaselineComparison{}) {
+				t.Errorf("IsEmpty() = %v", got.IsEmpty())
+			}
+		})
+	}
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	baseline := Baseline{
+		Pattern:   "HEATER_IMPL",
+		Created:   "2024-01-02T03:04:05Z",
+		TestCases: []TcJson{{Id: "4AP2-1001", Setup: "Bench A", Chain: []string{"power"}, Matches: []MatchJson{}}},
+	}
+	if err := SaveBaseline(path, baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, baseline) {
+		t.Errorf("loaded %+v, want %+v", loaded, baseline)
+	}
+}
@@ -0,0 +1,239 @@
+package repo_search
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var pythonClassDeclPattern = regexp.MustCompile(
+	`^(?P<indent>[ \t]*)class[ \t]+(?P<name>\w+)[ \t]*(?:\((?P<bases>[^)]*)\))?[ \t]*:`,
+)
+
+// PythonClass Class declaration with the methods it defines
+type PythonClass struct {
+	Name string
+	File string
+	// Bases Keys of the base classes (see ClassKey). Bases that are not in the
+	// repo are kept as written.
+	Bases []string
+	// Methods Method name -> whether the method calls the implementation of a base class
+	Methods map[string]bool
+}
+
+// ClassIndex Inheritance graph of all python classes in the repo
+type ClassIndex struct {
+	classes    map[string]*PythonClass
+	subclasses map[string][]string
+	byName     map[string][]string
+}
+
+func ClassKey(module, name string) string {
+	return module + "." + name
+}
+
+func BuildClassIndex(texts map[string]string, imports *ImportIndex) *ClassIndex {
+	index := &ClassIndex{
+		classes:    map[string]*PythonClass{},
+		subclasses: map[string][]string{},
+		byName:     map[string][]string{},
+	}
+
+	rawBases := map[string][]string{}
+	for file, text := range texts {
+		lang, ok := LanguageForFile(file).(ModuleLanguage)
+		if !ok {
+			continue
+		}
+		module := imports.Module(file)
+		for _, class := range lang.Classes(text) {
+			key := ClassKey(module, class.Name)
+			index.classes[key] = &PythonClass{
+				Name:    class.Name,
+				File:    file,
+				Methods: class.Methods,
+			}
+			index.byName[class.Name] = append(index.byName[class.Name], key)
+			rawBases[key] = class.Bases
+		}
+	}
+
+	// Bases can only be resolved once all classes are known
+	for key, class := range index.classes {
+		for _, base := range rawBases[key] {
+			baseKey := index.resolveBase(class.File, base, imports)
+			class.Bases = append(class.Bases, baseKey)
+			index.subclasses[baseKey] = append(index.subclasses[baseKey], key)
+		}
+	}
+
+	return index
+}
+
+// resolveBase Finds the class a base expression (i.e. `Base` or `module.Base`)
+// in file refers to. Imports of the file are followed first, then classes of
+// the same module and finally a class with that name anywhere in the repo.
+func (idx *ClassIndex) resolveBase(file, base string, imports *ImportIndex) string {
+	module := imports.Module(file)
+	prefix, name := "", base
+	if dot := strings.LastIndex(base, "."); dot != -1 {
+		prefix, name = base[:dot], base[dot+1:]
+	}
+
+	for _, imp := range imports.imports[file] {
+		if prefix == "" && imp.Alias == name && imp.Name != "" {
+			return ClassKey(imp.Module, imp.Name)
+		}
+		if prefix != "" && imp.Name == "" && (imp.Alias == prefix || imp.Module == prefix) {
+			return ClassKey(imp.Module, name)
+		}
+	}
+
+	if prefix == "" {
+		if _, ok := idx.classes[ClassKey(module, name)]; ok {
+			return ClassKey(module, name)
+		}
+	}
+
+	if keys := idx.byName[name]; len(keys) == 1 {
+		return keys[0]
+	}
+	return base
+}
+
+// SubclassesReaching Returns the subclasses (file -> class names) through which
+// calls of method (declared in class className of file definedIn) can reach its
+// implementation. These are subclasses that inherit the method or call it
+// through super() when overriding it. hierarchy contains the files of the class
+// and all its subclasses, including those that shadow the method.
+func (idx *ClassIndex) SubclassesReaching(
+	definedIn, className, method string,
+	imports *ImportIndex,
+) (reaching map[string][]string, hierarchy map[string]bool) {
+	reaching = map[string][]string{}
+	hierarchy = map[string]bool{definedIn: true}
+
+	queue := []string{ClassKey(imports.Module(definedIn), className)}
+	seen := map[string]bool{queue[0]: true}
+	for len(queue) > 0 {
+		baseKey := queue[0]
+		queue = queue[1:]
+
+		for _, subKey := range idx.subclasses[baseKey] {
+			if seen[subKey] {
+				continue
+			}
+			seen[subKey] = true
+
+			sub := idx.classes[subKey]
+			hierarchy[sub.File] = true
+
+			callsSuper, overrides := sub.Methods[method]
+			if overrides && !callsSuper {
+				warningTxt := fmt.Sprintf(
+					"%s.%s overrides %s.%s without calling it. Not following calls through %s.",
+					sub.Name, method, className, method, sub.Name,
+				)
+				log.Println(WarningStyle.Render(warningTxt))
+				// Subclasses of sub inherit the override -> they can't reach the matched implementation
+				continue
+			}
+
+			reaching[sub.File] = append(reaching[sub.File], sub.Name)
+			queue = append(queue, subKey)
+		}
+	}
+
+	return reaching, hierarchy
+}
+
+// ClassDeclaration Class as declared in a file (bases as written)
+type ClassDeclaration struct {
+	Name  string
+	Bases []string
+	// Methods Method name -> whether the method calls the implementation of a base class
+	Methods map[string]bool
+}
+
+// ConstructorName Instances are initialized by __init__
+func (Python) ConstructorName() string {
+	return "__init__"
+}
+
+func (Python) Classes(text string) []ClassDeclaration {
+	classes := []ClassDeclaration{}
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		match := pythonClassDeclPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		class := ClassDeclaration{
+			Name:    match[pythonClassDeclPattern.SubexpIndex("name")],
+			Methods: map[string]bool{},
+		}
+		for _, base := range splitTopLevel(match[pythonClassDeclPattern.SubexpIndex("bases")], ',') {
+			base = strings.TrimSpace(base)
+			// Skip keyword arguments (i.e. metaclass=...) and generic parameters
+			if base == "" || strings.Contains(base, "=") {
+				continue
+			}
+			if idx := strings.Index(base, "["); idx != -1 {
+				base = base[:idx]
+			}
+			class.Bases = append(class.Bases, base)
+		}
+
+		classIndent := indentation(line)
+		methodIndent := -1
+		methodName := ""
+		methodBody := []string{}
+		endMethod := func() {
+			if methodName != "" {
+				class.Methods[methodName] = callsSuperMethod(methodName, methodBody)
+			}
+		}
+
+		for _, bodyLine := range lines[i+1:] {
+			if strings.TrimSpace(bodyLine) == "" {
+				continue
+			}
+			indent := indentation(bodyLine)
+			if indent <= classIndent {
+				break
+			}
+
+			trimmed := strings.TrimSpace(bodyLine)
+			isDef := strings.HasPrefix(trimmed, "def ") || strings.HasPrefix(trimmed, "async def ")
+			if isDef && (methodIndent == -1 || indent <= methodIndent) {
+				endMethod()
+				methodIndent = indent
+				methodName = strings.TrimSpace(MatchContainerName(MethodContainer, bodyLine))
+				methodBody = nil
+				continue
+			}
+			methodBody = append(methodBody, bodyLine)
+		}
+		endMethod()
+
+		classes = append(classes, class)
+	}
+
+	return classes
+}
+
+// callsSuperMethod Checks for `super().method(` or `Base.method(self` in body
+func callsSuperMethod(method string, body []string) bool {
+	quoted := regexp.QuoteMeta(method)
+	superCall := regexp.MustCompile(
+		fmt.Sprintf(`super\([^)]*\)\s*\.\s*%s\s*\(|\b\w+\.%s\s*\(\s*self\b`, quoted, quoted),
+	)
+	return superCall.MatchString(strings.Join(body, "\n"))
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
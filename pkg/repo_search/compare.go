@@ -0,0 +1,93 @@
+package repo_search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChainChange TC that is reached in both searches but through different chains
+type ChainChange struct {
+	Tc       TestCase
+	OldChain []string
+}
+
+// TcComparison Difference between the TCs reached by the same search in an
+// old and a new revision
+type TcComparison struct {
+	Added        []TestCase
+	Removed      []TestCase
+	ChainChanged []ChainChange
+}
+
+func CompareTestCases(oldTcs, newTcs TestCasesMap) TcComparison {
+	comparison := TcComparison{}
+
+	for _, tc := range SortedTcs(newTcs) {
+		oldTc, ok := oldTcs[tc.info.id]
+		if !ok {
+			comparison.Added = append(comparison.Added, tc)
+			continue
+		}
+		if !equalStrings(oldTc.chain, tc.chain) {
+			comparison.ChainChanged = append(comparison.ChainChanged, ChainChange{tc, oldTc.chain})
+		}
+	}
+
+	for _, tc := range SortedTcs(oldTcs) {
+		if _, ok := newTcs[tc.info.id]; !ok {
+			comparison.Removed = append(comparison.Removed, tc)
+		}
+	}
+
+	return comparison
+}
+
+func (c TcComparison) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.ChainChanged) == 0
+}
+
+// Report Styled summary of the differences between oldRev and newRev
+func (c TcComparison) Report(oldRev, newRev string) string {
+	lines := []string{
+		ImportantStyle.Render(fmt.Sprintf("Comparison of %s with %s:", newRev, oldRev)),
+	}
+	if c.IsEmpty() {
+		lines = append(lines, InfoStyle.Render("Same TCs are reached through the same chains"))
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, InfoStyle.Render(fmt.Sprintf("Newly reached TCs (%d):", len(c.Added))))
+	for _, tc := range c.Added {
+		lines = append(lines, fmt.Sprintf("\t%s: %s", tc.info.id, formatChain(tc.chain)))
+	}
+
+	lines = append(lines, WarningStyle.Render(fmt.Sprintf("No longer reached TCs (%d):", len(c.Removed))))
+	for _, tc := range c.Removed {
+		lines = append(lines, fmt.Sprintf("\t%s: %s", tc.info.id, formatChain(tc.chain)))
+	}
+
+	lines = append(lines, InfoStyle.Render(fmt.Sprintf("TCs with changed chain (%d):", len(c.ChainChanged))))
+	for _, change := range c.ChainChanged {
+		lines = append(lines, fmt.Sprintf("\t%s:", change.Tc.info.id))
+		lines = append(lines, fmt.Sprintf("\t\t%s: %s", oldRev, formatChain(change.OldChain)))
+		lines = append(lines, fmt.Sprintf("\t\t%s: %s", newRev, formatChain(change.Tc.chain)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func formatChain(chain []string) string {
+	return strings.Join(chain, " -> ")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
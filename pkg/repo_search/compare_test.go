@@ -0,0 +1,83 @@
+package repo_search
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// compareFixtureTree TCs that reach HEATER_IMPL through several chains
+// (fixtures requested directly and by other fixtures, methods and imports)
+var compareFixtureTree = fstest.MapFS{
+	"lib/hal/heater.py": {Data: []byte(`class Heater:
+    def power(self, value):
+        HEATER_IMPL(value)
+
+    def off(self):
+        self.power(0)
+`)},
+	"lib/hal/__init__.py": {Data: []byte("")},
+	"test_cases/heater/conftest.py": {Data: []byte(`import pytest
+from lib.hal.heater import Heater
+
+@pytest.fixture
+def heater():
+    h = Heater()
+    h.power(10)
+    return h
+
+@pytest.fixture
+def bench(heater):
+    return heater
+
+@pytest.fixture(autouse=True)
+def cleanup():
+    Heater().off()
+`)},
+	"test_cases/heater/test_001_power.py": {Data: []byte(`"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+def test_001_power(heater, bench):
+    heater.power(5)
+`)},
+	"test_cases/heater/test_002_off.py": {Data: []byte(`"""
+Polarion ID: 4AP2-1002
+Setup: Bench A
+"""
+def test_002_off(bench):
+    bench.off()
+`)},
+	"test_cases/heater/test_003_none.py": {Data: []byte(`"""
+Polarion ID: 4AP2-1003
+Setup: Bench A
+"""
+def test_003_none():
+    pass
+`)},
+}
+
+func TestCompareSameRevision(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	search := func() TestCasesMap {
+		repo := NewRepo([]Root{{Dir: "repo", FS: compareFixtureTree}}, FileFilter{FileTypes: []string{".py"}}, nil)
+		defer repo.Close()
+		return SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 10)
+	}
+
+	first := search()
+	if len(first) != 3 {
+		t.Fatalf("expected 3 reached TCs, got %s", first)
+	}
+
+	for i := 0; i < 20; i++ {
+		comparison := CompareTestCases(first, search())
+		if !comparison.IsEmpty() {
+			t.Fatalf("run %d: comparison of the same revision isn't empty:\n%s", i, comparison.Report("old", "new"))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package repo_search
+
+import (
+	"io"
+	"testing"
+)
+
+func TestPythonConstructorScope(t *testing.T) {
+	lines := "class Heater(Device):\n    def __init__(self, v):\n        super().__init__()\n        "
+	want := Scope{Name: "Heater", Kind: ConstructorScope, Class: "Heater"}
+	if scope := (Python{}).EnclosingScope(lines, "        HEATER_IMPL(v)"); scope != want {
+		t.Errorf("scope %+v, want %+v", scope, want)
+	}
+
+	// __init__ of a function (not a class) can't be searched for
+	if scope := (Python{}).EnclosingScope("def __init__(v):\n    ", "    HEATER_IMPL(v)"); scope != (Scope{}) {
+		t.Errorf("scope %+v, want none", scope)
+	}
+}
+
+func TestConstructorMatches(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/__init__.py": "",
+		"lib/heater.py": `class Heater:
+    def __init__(self, v):
+        HEATER_IMPL(v)
+
+    def power(self, v):
+        pass
+
+
+def make_heater():
+    return Heater(1)
+`,
+		"lib/boost.py": `from lib.heater import Heater
+
+class BoostHeater(Heater):
+    pass
+
+class CustomHeater(Heater):
+    def __init__(self):
+        pass
+
+class ChainedHeater(Heater):
+    def __init__(self):
+        super().__init__(2)
+`,
+		"test_cases/heater/test_001_heater.py": tcText("4AP2-1001",
+			"from lib.heater import Heater\n\ndef test_001_heater():\n    Heater(5)\n"),
+		"test_cases/heater/test_002_boost.py": tcText("4AP2-1002",
+			"from lib.boost import BoostHeater\n\ndef test_002_boost():\n    BoostHeater(5)\n"),
+		"test_cases/heater/test_003_factory.py": tcText("4AP2-1003",
+			"from lib.heater import make_heater\n\ndef test_003_factory():\n    make_heater()\n"),
+		"test_cases/heater/test_004_chained.py": tcText("4AP2-1004",
+			"from lib.boost import ChainedHeater\n\ndef test_004_chained():\n    ChainedHeater()\n"),
+		// Overrides __init__ without calling it
+		"test_cases/heater/test_005_custom.py": tcText("4AP2-1005",
+			"from lib.boost import CustomHeater\n\ndef test_005_custom():\n    CustomHeater()\n"),
+		// Same class name from another module
+		"test_cases/heater/test_006_other.py": tcText("4AP2-1006",
+			"from lib.other import Heater\n\ndef test_006_other():\n    Heater(5)\n"),
+	})
+	repo.Output = io.Discard
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+	want := []string{"4AP2-1001", "4AP2-1002", "4AP2-1003", "4AP2-1004"}
+	if got := sortedTcIds(testCases); !equalStrings(got, want) {
+		t.Errorf("TCs %q, want %q", got, want)
+	}
+}
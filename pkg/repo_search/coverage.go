@@ -0,0 +1,134 @@
+package repo_search
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DeclarationCoverage TCs that reach a declaration
+type DeclarationCoverage struct {
+	Declaration
+	RelPath   string
+	TestCases TestCasesMap
+}
+
+// LibraryFiles Returns the repo files inside of dir. The dir is either
+// relative to one of the roots or a path itself.
+func LibraryFiles(repo *Repo, dir string) []string {
+	if files := repo.FilesIn(dir); len(files) > 0 && dir != "" {
+		return files
+	}
+
+	files := []string{}
+	for _, root := range repo.Roots {
+		for _, file := range repo.FilesIn(root.File(dir)) {
+			if !containsString(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// TcIds Returns the IDs of the TCs that reach the declaration (sorted)
+func (c DeclarationCoverage) TcIds() []string {
+	ids := []string{}
+	for _, tc := range SortedTcs(c.TestCases) {
+		ids = append(ids, tc.info.id)
+	}
+	return ids
+}
+
+// UnsupportedFiles Returns the (non TC) files in which no declarations can be
+// found cause their language doesn't know where a declaration ends. Their
+// functions are missing from the coverage.
+func UnsupportedFiles(files []string) []string {
+	unsupported := []string{}
+	for _, file := range files {
+		lang := LanguageForFile(file)
+		if _, ok := lang.(BlockLanguage); !ok && !lang.IsTcFile(file) {
+			unsupported = append(unsupported, file)
+		}
+	}
+	return unsupported
+}
+
+// Coverage Searches the TCs that reach each function and method declared in
+// files (the same way as for a match inside of it)
+func Coverage(repo *Repo, files []string, degreesOfSeparation int) []DeclarationCoverage {
+	coverage := []DeclarationCoverage{}
+	for _, declaration := range FindDeclarations(repo, files) {
+		testCases := SearchForScopeUsages(repo, SearchedSet{}, declaration.File, declaration.Scope, degreesOfSeparation)
+		coverage = append(coverage, DeclarationCoverage{
+			Declaration: declaration,
+			RelPath:     repo.RelPath(declaration.File),
+			TestCases:   testCases,
+		})
+	}
+
+	sort.SliceStable(coverage, func(i, j int) bool {
+		if coverage[i].RelPath != coverage[j].RelPath {
+			return coverage[i].RelPath < coverage[j].RelPath
+		}
+		return coverage[i].Line < coverage[j].Line
+	})
+	return coverage
+}
+
+// Untested Returns the declarations that no TC reaches
+func Untested(coverage []DeclarationCoverage) []DeclarationCoverage {
+	untested := []DeclarationCoverage{}
+	for _, declaration := range coverage {
+		if len(declaration.TestCases) == 0 {
+			untested = append(untested, declaration)
+		}
+	}
+	return untested
+}
+
+var CoverageCsvHeader = []string{"File", "Line", "Function", "TCs"}
+
+// CoverageCsvRecords Matrix with a row per declaration and a column per TC
+// (x if the TC reaches the declaration)
+func CoverageCsvRecords(coverage []DeclarationCoverage) [][]string {
+	allTcs := TestCasesMap{}
+	for _, declaration := range coverage {
+		allTcs = UpdateMap(allTcs, declaration.TestCases)
+	}
+	tcs := SortedTcs(allTcs)
+
+	header := append([]string{}, CoverageCsvHeader...)
+	for _, tc := range tcs {
+		header = append(header, tc.info.id)
+	}
+
+	records := [][]string{header}
+	for _, declaration := range coverage {
+		record := []string{
+			declaration.RelPath,
+			strconv.Itoa(declaration.Line),
+			declaration.Name(),
+			strconv.Itoa(len(declaration.TestCases)),
+		}
+		for _, tc := range tcs {
+			cell := ""
+			if _, ok := declaration.TestCases[tc.info.id]; ok {
+				cell = "x"
+			}
+			record = append(record, cell)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// CoverageSummary Returns how many of the declarations are reached by at least one TC
+func CoverageSummary(coverage []DeclarationCoverage) string {
+	tested := len(coverage) - len(Untested(coverage))
+	percent := 0.0
+	if len(coverage) > 0 {
+		percent = float64(tested) * 100 / float64(len(coverage))
+	}
+	return fmt.Sprintf("%d of %d functions reached by TCs (%.1f%%)", tested, len(coverage), percent)
+}
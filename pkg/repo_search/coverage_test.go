@@ -0,0 +1,119 @@
+package repo_search
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCoverage(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.py": "def heat(v):\n    pass\n\ndef unused():\n    pass\n",
+		"lib/heater.resource": "*** Keywords ***\nSet Power\n    [Arguments]    ${value}\n    Log    ${value}\n\n" +
+			"Switch Off\n    Log    0\n",
+		"lib/sim.c": "int sim_power(int v)\n{\n    return v;\n}\n\nstatic int sim_unused(void) {\n    return 0;\n}\n",
+		"test_cases/heater/test_001_power.py": `"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+from lib.heater import heat
+
+def test_001_power():
+    heat(5)
+`,
+		"test_cases/heater/test_002_sim.py": `"""
+Polarion ID: 4AP2-1002
+Setup: Bench A
+"""
+from lib.heater import heat
+
+def test_002_sim(sim):
+    heat(sim.sim_power(1))
+`,
+		"test_cases/heater/heater.robot": `*** Settings ***
+Resource    ../../lib/heater.resource
+
+*** Test Cases ***
+Heater Power
+    [Documentation]    Polarion ID: 4AP2-1003
+    ...    Setup: Bench A
+    Set Power    10
+`,
+	})
+	repo.Output = io.Discard
+
+	coverage := Coverage(repo, LibraryFiles(repo, "lib"), 3)
+
+	want := []struct {
+		name string
+		tcs  []string
+	}{
+		{"heat", []string{"4AP2-1001", "4AP2-1002"}},
+		{"unused", nil},
+		{"Set Power", []string{"4AP2-1003"}},
+		{"Switch Off", nil},
+		{"sim_power", []string{"4AP2-1002"}},
+		{"sim_unused", nil},
+	}
+	if len(coverage) != len(want) {
+		names := []string{}
+		for _, declaration := range coverage {
+			names = append(names, declaration.Name())
+		}
+		t.Fatalf("declarations %q, want %d", names, len(want))
+	}
+	for i, declaration := range coverage {
+		if declaration.Name() != want[i].name || !equalStrings(declaration.TcIds(), want[i].tcs) {
+			t.Errorf("%d: %s reached by %q, want %s reached by %q",
+				i, declaration.Name(), declaration.TcIds(), want[i].name, want[i].tcs)
+		}
+	}
+
+	untested := []string{}
+	for _, declaration := range Untested(coverage) {
+		untested = append(untested, declaration.Name())
+	}
+	if !equalStrings(untested, []string{"unused", "Switch Off", "sim_unused"}) {
+		t.Errorf("untested %q", untested)
+	}
+	if summary := CoverageSummary(coverage); summary != "3 of 6 functions reached by TCs (50.0%)" {
+		t.Errorf("summary %q", summary)
+	}
+
+	records := CoverageCsvRecords(coverage)
+	wantRecords := [][]string{
+		{"File", "Line", "Function", "TCs", "4AP2-1001", "4AP2-1002", "4AP2-1003"},
+		{"lib/heater.py", "1", "heat", "2", "x", "x", ""},
+		{"lib/heater.py", "4", "unused", "0", "", "", ""},
+		{"lib/heater.resource", "2", "Set Power", "1", "", "", "x"},
+		{"lib/heater.resource", "6", "Switch Off", "0", "", "", ""},
+		{"lib/sim.c", "1", "sim_power", "1", "", "x", ""},
+		{"lib/sim.c", "6", "sim_unused", "0", "", "", ""},
+	}
+	if len(records) != len(wantRecords) {
+		t.Fatalf("got %d records, want %d", len(records), len(wantRecords))
+	}
+	for i := range records {
+		if !equalStrings(records[i], wantRecords[i]) {
+			t.Errorf("record %d = %q, want %q", i, records[i], wantRecords[i])
+		}
+	}
+}
+
+func TestUnsupportedFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/heater.py":  {Data: []byte("def power(v):\n    pass\n")},
+		"lib/notes.txt":  {Data: []byte("power\n")},
+		"lib/sim.c":      {Data: []byte("int sim_power(int v) {\n    return v;\n}\n")},
+		"lib/heater.ini": {Data: []byte("power = 1\n")},
+	}
+	repo := NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py", ".txt", ".c", ".ini"}}, nil)
+
+	unsupported := []string{}
+	for _, file := range UnsupportedFiles(LibraryFiles(repo, "lib")) {
+		unsupported = append(unsupported, repo.RelPath(file))
+	}
+	if !equalStrings(unsupported, []string{"lib/heater.ini", "lib/notes.txt"}) {
+		t.Errorf("unsupported files %q", unsupported)
+	}
+}
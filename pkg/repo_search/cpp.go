@@ -0,0 +1,239 @@
+package repo_search
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	cppFunctionPattern = regexp.MustCompile(
+		`^\s*(?:[A-Za-z_][\w:<>,]*[\s\*&]+)+[\*&]*(?P<name>[A-Za-z_~][\w:~]*)\s*\(`,
+	)
+	// Function header right before an opening brace. Allows for
+	// qualifiers (const, override...) and constructor initializer lists.
+	cppFunctionHeaderPattern = regexp.MustCompile(
+		`(?P<name>[A-Za-z_~][\w:~]*)\s*\([^()]*(?:\([^()]*\)[^()]*)*\)\s*(?:const|noexcept|override|final|volatile|&|\s)*(?::[^{]*)?$`,
+	)
+	cppClassPattern = regexp.MustCompile(`^\s*(?:class|struct)\s+(?P<name>[A-Za-z_]\w*)`)
+)
+
+// Names which look like functions in a header but aren't
+var cppKeywords = map[string]bool{
+	"if":       true,
+	"for":      true,
+	"while":    true,
+	"switch":   true,
+	"catch":    true,
+	"return":   true,
+	"sizeof":   true,
+	"defined":  true,
+	"decltype": true,
+}
+
+// Cpp Covers both C and C++ sources and headers
+type Cpp struct{}
+
+func (Cpp) Name() string {
+	return "c/c++"
+}
+
+func (Cpp) Extensions() []string {
+	return []string{".c", ".h", ".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}
+}
+
+// DeclarationName Only definitions are considered declarations. Prototypes
+// (ending with ;) are treated as regular matches so that a function with a
+// prototype in a header is not reported as declared twice.
+func (Cpp) DeclarationName(t ContainerType, line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasSuffix(trimmed, ";") || strings.HasPrefix(trimmed, "#") {
+		return ""
+	}
+
+	if t == ClassContainer {
+		match := cppClassPattern.FindStringSubmatch(line)
+		if match == nil {
+			return ""
+		}
+		return match[cppClassPattern.SubexpIndex("name")]
+	}
+
+	// Assignments and returns that contain calls are not declarations
+	if strings.HasPrefix(trimmed, "return") || strings.Contains(strings.Split(trimmed, "(")[0], "=") {
+		return ""
+	}
+
+	match := cppFunctionPattern.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+
+	name := cppUnqualifiedName(match[cppFunctionPattern.SubexpIndex("name")])
+	if cppKeywords[name] {
+		return ""
+	}
+	return name
+}
+
+func (Cpp) UsagePattern(name string) *regexp.Regexp {
+	return WordPattern(name)
+}
+
+// EnclosingScope Finds the innermost unclosed brace block that belongs to a
+// function definition. Blocks of control statements, lambdas, classes and
+// namespaces are skipped in favour of the enclosing function.
+func (Cpp) EnclosingScope(pretext, _ string) Scope {
+	code := cppStripComments(pretext)
+
+	openBraces := []int{}
+	for i, c := range code {
+		switch c {
+		case '{':
+			openBraces = append(openBraces, i)
+		case '}':
+			if len(openBraces) > 0 {
+				openBraces = openBraces[:len(openBraces)-1]
+			}
+		}
+	}
+
+	for i := len(openBraces) - 1; i >= 0; i-- {
+		header := code[:openBraces[i]]
+		// Header starts after the end of the previous statement or block
+		headerStart := strings.LastIndexAny(header, ";{}") + 1
+		header = strings.TrimSpace(header[headerStart:])
+
+		match := cppFunctionHeaderPattern.FindStringSubmatch(header)
+		if match == nil {
+			continue
+		}
+
+		name := cppUnqualifiedName(match[cppFunctionHeaderPattern.SubexpIndex("name")])
+		if cppKeywords[name] {
+			continue
+		}
+
+		// Do not use destructors as containing method cause we can't search for them
+		if strings.HasPrefix(name, "~") {
+			return Scope{}
+		}
+		return Scope{Name: name}
+	}
+
+	return Scope{}
+}
+
+// BlockEnd Returns the line of the brace that closes the body of the function
+// declared on lines[defIdx] (defIdx itself for prototypes)
+func (Cpp) BlockEnd(lines []string, defIdx int) int {
+	code := cppStripComments(strings.Join(lines[defIdx:], "\n"))
+
+	line, depth := defIdx, 0
+	for _, c := range code {
+		switch c {
+		case '\n':
+			line++
+		case ';':
+			if depth == 0 {
+				return defIdx
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return line
+			}
+		}
+	}
+	return len(lines) - 1
+}
+
+// IsGenerated Protobuf generated sources are skipped
+func (Cpp) IsGenerated(path string) bool {
+	for _, suffix := range []string{".pb.h", ".pb.c", ".pb.cc", ".pb-c.h", ".pb-c.c"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTcFile TCs are only written in python
+func (Cpp) IsTcFile(_ string) bool {
+	return false
+}
+
+func (Cpp) TestCaseTexts(_ string, _ int) []string {
+	return nil
+}
+
+// cppUnqualifiedName Returns only the method name from a qualified name (i.e. Class::method)
+func cppUnqualifiedName(name string) string {
+	parts := strings.Split(name, "::")
+	return parts[len(parts)-1]
+}
+
+// cppStripComments Replaces comments, string/char literals and preprocessor
+// lines with spaces. Newlines are kept so offsets stay the same.
+func cppStripComments(text string) string {
+	out := []byte(text)
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	lineStart := true
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\n':
+			lineStart = true
+			continue
+		case c == ' ' || c == '\t':
+			continue
+		case lineStart && c == '#':
+			end := strings.Index(text[i:], "\n")
+			if end == -1 {
+				end = len(text) - i
+			}
+			blank(i, i+end)
+			i += end - 1
+		case strings.HasPrefix(text[i:], "//"):
+			end := strings.Index(text[i:], "\n")
+			if end == -1 {
+				end = len(text) - i
+			}
+			blank(i, i+end)
+			i += end - 1
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end == -1 {
+				end = len(text) - i
+			} else {
+				end += 4
+			}
+			blank(i, i+end)
+			i += end - 1
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(text) && text[end] != c && text[end] != '\n' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(text) && text[end] == '\n' {
+				// Unterminated literal -> don't swallow the newline
+				end--
+			}
+			blank(i, end+1)
+			i = end
+		}
+		lineStart = false
+	}
+	return string(out)
+}
@@ -0,0 +1,103 @@
+package repo_search
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCppEnclosingScope(t *testing.T) {
+	tests := []struct {
+		name string
+		// text Code before the match (marked by |)
+		text string
+		want string
+	}{
+		{"function", "int sim_power(int v)\n{\n    return |", "sim_power"},
+		{"same line brace", "static int sim_power(int v) {\n    |", "sim_power"},
+		{"control blocks", "void f(int v) {\n    if (v) {\n        for (;;) {\n            |", "f"},
+		{"method", "void Heater::power(int v) const {\n    |", "power"},
+		{"constructor initializer list", "Heater::Heater(int v) : value_(v), other_(0) {\n    |", "Heater"},
+		{"method in class", "class Heater {\npublic:\n    int power(int v) override {\n        |", "power"},
+		{"lambda", "void f() {\n    auto g = [](int v) {\n        |", "f"},
+		{"namespace", "namespace sim {\nint f(int v) {\n    |", "f"},
+		{"after function", "int f(void) {\n    return 0;\n}\nint g = |", ""},
+		{"class body", "class Heater {\n    int |", ""},
+		{"prototype", "int f(void);\n|", ""},
+		{"braces in comments and strings", "int f(void) {\n    // }\n    /* } */\n    puts(\"}\");\n    |", "f"},
+		{"preprocessor", "#define X() {\nint f(void) {\n    |", "f"},
+		{"destructor", "Heater::~Heater() {\n    |", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pretext := strings.TrimSuffix(test.text, "|")
+			if scope := (Cpp{}).EnclosingScope(pretext, ""); scope.Name != test.want {
+				t.Errorf("scope %q, want %q", scope.Name, test.want)
+			}
+		})
+	}
+}
+
+func TestCppDeclarationName(t *testing.T) {
+	tests := []struct {
+		line string
+		t    ContainerType
+		want string
+	}{
+		{"int sim_power(int v)", MethodContainer, "sim_power"},
+		{"static const char *name(void) {", MethodContainer, "name"},
+		{"void Heater::power(int v) const {", MethodContainer, "power"},
+		{"int sim_power(int v);", MethodContainer, ""},
+		{"    return sim_power(v);", MethodContainer, ""},
+		{"    int x = sim_power(v);", MethodContainer, ""},
+		{"    if (sim_power(v)) {", MethodContainer, ""},
+		{"#define POWER(v) sim_power(v)", MethodContainer, ""},
+		{"class Heater : public Device {", ClassContainer, "Heater"},
+		{"struct heater_state {", ClassContainer, "heater_state"},
+		{"struct heater_state state;", ClassContainer, ""},
+	}
+
+	for _, test := range tests {
+		if got := (Cpp{}).DeclarationName(test.t, test.line); got != test.want {
+			t.Errorf("%q: declaration %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestCppMatchReachesTc(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"sim/sim.c": `#include "sim.h"
+
+static int apply(int v)
+{
+    return HEATER_IMPL(v);
+}
+
+int sim_power(int v) {
+    if (v > 0) {
+        return apply(v);
+    }
+    return 0;
+}
+`,
+		"sim/sim.h": "int sim_power(int v);\n",
+		"test_cases/sim/test_001_sim.py": `"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+def test_001_sim(dll):
+    dll.sim_power(10)
+`,
+	})
+	repo.Output = io.Discard
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+	tc, ok := testCases["4AP2-1001"]
+	if !ok || len(testCases) != 1 {
+		t.Fatalf("found %d TCs, want 4AP2-1001", len(testCases))
+	}
+	if want := []string{"HEATER_IMPL", "apply", "sim_power"}; !equalStrings(tc.chain, want) {
+		t.Errorf("chain %q, want %q", tc.chain, want)
+	}
+}
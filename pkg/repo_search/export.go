@@ -1,10 +1,13 @@
 package repo_search
 
 import (
+	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -62,15 +65,10 @@ func FindApprovedTcs(
 	return approved, notApproved
 }
 
-func CreateProtocolXml(testCases TestCasesMap, wiExportPath string) string {
+func CreateProtocolXml(testCases TestCasesMap, workItems WorkItems) string {
 	protocols := ""
 	tcBySetup := GetTcBySetup(testCases)
 
-	var workItems WorkItems
-	if wiExportPath != "" {
-		workItems = GetWorkItemsFromPolarionExport(wiExportPath)
-	}
-
 	// if we have workitems information, Include Approved/NotApproved info to protocols txt
 	approvedTxt := ""
 	if workItems != nil {
@@ -108,17 +106,186 @@ func CreateProtocolXml(testCases TestCasesMap, wiExportPath string) string {
 	return protocols
 }
 
-func CreateXml(template, outPath, searchPattern, protocols string) string {
+// XmlText Fills the verification loop template with the search pattern and protocols
+func XmlText(template, searchPattern, protocols string) string {
 	template = strings.Replace(template, VlReplaceResults, protocols, 1)
-	template = strings.Replace(
+	return strings.Replace(
 		template,
 		VlReplaceSearchPattern,
 		fmt.Sprintf(SearchPatternTemplate, searchPattern),
 		1,
 	)
+}
+
+func CreateXml(template, outPath, searchPattern, protocols string) string {
+	return WriteXml(outPath, XmlText(template, searchPattern, protocols))
+}
 
+// WriteXml Writes text to outPath (with a timestamp added to the name)
+func WriteXml(outPath, text string) string {
 	outFilename := AddTimestampToFilename(outPath, ".xml")
-	err := os.WriteFile(outFilename, []byte(template), 0666)
+	err := os.WriteFile(outFilename, []byte(text), 0666)
+	if err != nil {
+		errorTxt := fmt.Sprintf("ERROR: Couldn't write to file %s: %v", outFilename, err)
+		log.Fatal(ErrorStyle.Render(errorTxt))
+	}
+
+	return outFilename
+}
+
+var (
+	TcCsvHeader    = []string{"ID", "Title", "Setup", "Estimate", "Seconds", "Status", "Risk Reduction Measures", "Script Path", "Script URL"}
+	MatchCsvHeader = []string{
+		"TC ID", "Search Term", "File", "Line", "Column", "Text", "Containing Method", "Context Before", "Context After",
+	}
+)
+
+// SortedTcs Returns test cases sorted by ID so that exports are stable between runs
+func SortedTcs(testCases TestCasesMap) []TestCase {
+	tcs := make([]TestCase, 0, len(testCases))
+	for _, tc := range testCases {
+		tcs = append(tcs, tc)
+	}
+	sort.Slice(tcs, func(i, j int) bool {
+		return tcs[i].info.id < tcs[j].info.id
+	})
+	return tcs
+}
+
+func TcCsvRecords(testCases TestCasesMap, workItems WorkItems) [][]string {
+	records := [][]string{TcCsvHeader}
+	for _, tc := range SortedTcs(testCases) {
+		var (
+			title  string
+			status string
+			rrm    string
+		)
+		if item, ok := workItems[tc.info.id]; ok {
+			title = item.Title
+			status = item.Status
+			rrm = strings.Join(item.RiskReductionMeasures, ", ")
+		}
+
+		records = append(records, []string{
+			tc.info.id,
+			title,
+			strings.TrimSpace(tc.info.setup),
+			tc.info.estimate,
+			strconv.Itoa(tc.DurationSec()),
+			status,
+			rrm,
+			tc.ScriptPath(),
+			tc.ScriptUrl(),
+		})
+	}
+	return records
+}
+
+// MatchCsvRecords One row per search result, incl. the ones outside of TCs and
+// the ones of the recursive searches. TC ID lists the TCs that contain the match.
+func MatchCsvRecords(results []RecordedResult) [][]string {
+	records := [][]string{MatchCsvHeader}
+	for _, recorded := range results {
+		match := recorded.result
+		ids := []string{}
+		for _, info := range match.testCases {
+			ids = append(ids, info.id)
+		}
+
+		records = append(records, []string{
+			strings.Join(ids, ", "),
+			recorded.SearchTerm,
+			match.relPath,
+			strconv.Itoa(match.line),
+			// Columns are 1-based like lines so that they match what editors show
+			strconv.Itoa(match.col + 1),
+			strings.TrimSpace(match.matchLineTxt),
+			match.usedIn.Name,
+			strings.Join(match.before, "\n"),
+			strings.Join(match.after, "\n"),
+		})
+	}
+	return records
+}
+
+// TcJson TC as exported to JSON. Title and status are only known from work items.
+type TcJson struct {
+	Id         string      `json:"id"`
+	Title      string      `json:"title,omitempty"`
+	Setup      string      `json:"setup"`
+	Estimate   string      `json:"estimate"`
+	Seconds    int         `json:"seconds"`
+	Status     string      `json:"status,omitempty"`
+	ScriptPath string      `json:"scriptPath"`
+	ScriptUrl  string      `json:"scriptUrl"`
+	Chain      []string    `json:"chain"`
+	Matches    []MatchJson `json:"matches"`
+}
+
+// MatchJson Search result inside of a TC as exported to JSON
+type MatchJson struct {
+	File             string   `json:"file"`
+	Line             int      `json:"line"`
+	Column           int      `json:"column"`
+	Text             string   `json:"text"`
+	ContainingMethod string   `json:"containingMethod"`
+	Before           []string `json:"before,omitempty"`
+	After            []string `json:"after,omitempty"`
+}
+
+func TcJsonRecords(testCases TestCasesMap, workItems WorkItems) []TcJson {
+	records := []TcJson{}
+	for _, tc := range SortedTcs(testCases) {
+		record := TcJson{
+			Id:         tc.info.id,
+			Setup:      strings.TrimSpace(tc.info.setup),
+			Estimate:   tc.info.estimate,
+			Seconds:    tc.DurationSec(),
+			ScriptPath: tc.ScriptPath(),
+			ScriptUrl:  tc.ScriptUrl(),
+			Chain:      tc.chain,
+			Matches:    []MatchJson{},
+		}
+		if item, ok := workItems[tc.info.id]; ok {
+			record.Title = item.Title
+			record.Status = item.Status
+		}
+
+		for _, match := range tc.matches {
+			record.Matches = append(record.Matches, MatchJson{
+				File:             match.relPath,
+				Line:             match.line,
+				Column:           match.col + 1,
+				Text:             strings.TrimSpace(match.matchLineTxt),
+				ContainingMethod: match.usedIn.Name,
+				Before:           match.before,
+				After:            match.after,
+			})
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// CreateCsv Writes records to a timestamped csv file. If outPath has a .tsv
+// extension the values are tab separated instead.
+func CreateCsv(outPath string, records [][]string) string {
+	ext := filepath.Ext(outPath)
+	outFilename := AddTimestampToFilename(outPath, ext)
+
+	f, err := os.Create(outFilename)
+	if err != nil {
+		errorTxt := fmt.Sprintf("ERROR: Couldn't create file %s: %v", outFilename, err)
+		log.Fatal(ErrorStyle.Render(errorTxt))
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if ext == ".tsv" {
+		w.Comma = '\t'
+	}
+
+	err = w.WriteAll(records)
 	if err != nil {
 		errorTxt := fmt.Sprintf("ERROR: Couldn't write to file %s: %v", outFilename, err)
 		log.Fatal(ErrorStyle.Render(errorTxt))
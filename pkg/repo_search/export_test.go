@@ -0,0 +1,91 @@
+package repo_search
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// assertGolden Compares the content of file with testdata/golden
+func assertGolden(t *testing.T, file, golden string) {
+	t.Helper()
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldenPath := filepath.Join("testdata", golden)
+	if *update {
+		if err := os.WriteFile(goldenPath, got, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from %s:\n%s\nwant:\n%s", file, goldenPath, got, want)
+	}
+}
+
+func TestMatchCsvRecords(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.py": "def power(v):\n    # Heater output\n    HEATER_IMPL(v)\n",
+		"lib/bench.py":  "from lib.heater import power\n\ndef warm_up():\n    power(10)\n",
+		"test_cases/heater/test_001_power.py": `"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+from lib.heater import power
+
+def test_001_power():
+    power(5)
+`,
+		"test_cases/heater/test_002_warm_up.py": `"""
+Polarion ID: 4AP2-1002
+Setup: Bench A
+"""
+from lib.bench import warm_up
+
+def test_002_warm_up():
+    warm_up()
+`,
+	})
+	repo.Output = io.Discard
+	repo.Context = MatchContext{Before: 1}
+	repo.Recorder = &ResultRecorder{}
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+	if len(testCases) != 2 {
+		t.Fatalf("found TCs %v, want 4AP2-1001 and 4AP2-1002", testCases)
+	}
+
+	// The match in lib and the usages of the functions it is in are exported
+	// as well as the matches in the TCs
+	records := MatchCsvRecords(repo.Recorder.Results())
+	for _, golden := range []string{"matches.csv", "matches.tsv"} {
+		file := CreateCsv(filepath.Join(t.TempDir(), golden), records)
+		assertGolden(t, file, golden)
+	}
+}
+
+func TestUpdateMapCopiesMatches(t *testing.T) {
+	existing := make([]SearchResult, 1, 2)
+	existing[0] = SearchResult{line: 1}
+	v1 := TestCasesMap{"4AP2-1001": {matches: existing}}
+
+	UpdateMap(v1, TestCasesMap{"4AP2-1001": {matches: []SearchResult{{line: 2}}}})
+	UpdateMap(v1, TestCasesMap{"4AP2-1002": {matches: []SearchResult{{line: 3}}}})
+	// Appending to the original slice must not change the merged matches
+	_ = append(existing, SearchResult{line: 4})
+
+	matches := v1["4AP2-1001"].matches
+	if len(matches) != 2 || matches[0].line != 1 || matches[1].line != 2 {
+		t.Errorf("merged matches %v, want lines 1 and 2", matches)
+	}
+}
@@ -1,26 +1,26 @@
 package repo_search
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type FileResult struct {
-	file    string
+	file string
+	// relPath Path of file relative to its root (used for reporting)
+	relPath string
 	matches []SearchResult
 	isTc    bool
-	tcInfo  TestCaseInfo
 }
 
 func (r FileResult) String() string {
-	out := FilenameStyle.Render(fmt.Sprintf("%s", r.file))
+	out := FilenameStyle.Render(fmt.Sprintf("%s", r.relPath))
 	out += "\n"
 
 	for _, match := range r.matches {
-		out += fmt.Sprintf("\t%s\n\n", match)
+		// Indent every line of multi-line matches and context
+		out += fmt.Sprintf("\t%s\n\n", strings.ReplaceAll(match.String(), "\n", "\n\t"))
 	}
 
 	return out
 }
-
-func (r *FileResult) RemoveMatch(idx int) {
-	r.matches[idx] = r.matches[len(r.matches)-1]
-	r.matches = r.matches[:len(r.matches)-1]
-}
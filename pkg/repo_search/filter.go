@@ -0,0 +1,253 @@
+package repo_search
+
+import (
+	"bufio"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const GitignoreFilename = ".gitignore"
+
+// DefaultExcludes Virtual environments, caches and VCS data are never worth searching
+var DefaultExcludes = []string{".venv", "site-packages", "__pycache__", ".git"}
+
+// FileFilter Decides which files of the searched directory are searched
+type FileFilter struct {
+	FileTypes []string
+	// Include Globs of files (or of their directories) to search. If empty all files are searched.
+	Include []string
+	// Exclude Globs of files and directories to skip
+	Exclude []string
+	// UseGitignore Skip files and directories ignored by .gitignore files
+	UseGitignore bool
+}
+
+// GlobPattern Glob in .gitignore syntax. A glob without a slash matches at any
+// depth, `**` matches any number of directories and a trailing slash matches
+// only directories.
+type GlobPattern struct {
+	pattern *regexp.Regexp
+	// base Slash separated directory (relative to the root) the glob is relative to
+	base    string
+	dirOnly bool
+	negate  bool
+}
+
+func NewGlobPattern(glob, base string) GlobPattern {
+	g := GlobPattern{base: base}
+	if strings.HasPrefix(glob, "!") {
+		g.negate = true
+		glob = glob[1:]
+	}
+	if strings.HasSuffix(glob, "/") {
+		g.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+
+	if strings.HasPrefix(glob, "/") {
+		glob = glob[1:]
+	} else if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+
+	pattern, err := regexp.Compile(globToRegexp(glob))
+	if err != nil {
+		// Invalid ranges (i.e. `[z-a]`) don't match anything in git either
+		pattern = regexp.MustCompile(neverMatches)
+	}
+	g.pattern = pattern
+	return g
+}
+
+func GlobPatterns(globs []string) []GlobPattern {
+	patterns := []GlobPattern{}
+	for _, glob := range globs {
+		patterns = append(patterns, NewGlobPattern(glob, ""))
+	}
+	return patterns
+}
+
+// Match Reports whether rel (slash separated path relative to the root) matches the glob
+func (g GlobPattern) Match(rel string, isDir bool) bool {
+	if g.dirOnly && !isDir {
+		return false
+	}
+	if g.base != "" {
+		if !strings.HasPrefix(rel, g.base+"/") {
+			return false
+		}
+		rel = rel[len(g.base)+1:]
+	}
+	return g.pattern.MatchString(rel)
+}
+
+// neverMatches Pattern of invalid globs (i.e. with a trailing backslash or an
+// unclosed bracket), git doesn't match anything with them either
+const neverMatches = `[^\x00-\x{10FFFF}]`
+
+// globToRegexp Converts glob to a regexp. A backslash escapes the next char
+// (also inside of brackets) like in .gitignore files.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(glob) {
+				return neverMatches
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			class, end := globClass(glob, i)
+			if end == -1 {
+				// Unclosed brackets are invalid
+				return neverMatches
+			}
+			b.WriteString(class)
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// globClass Converts the bracket expression that starts at glob[start] to a
+// regexp char class. Returns the index of its closing bracket (-1 if there is none).
+func globClass(glob string, start int) (string, int) {
+	var b strings.Builder
+	b.WriteString("[")
+
+	i := start + 1
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		b.WriteString("^")
+		i++
+	}
+	for first := i; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		// A closing bracket right after the opening one is a literal
+		case c == ']' && i > first:
+			b.WriteString("]")
+			return b.String(), i
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(classLiteral(glob[i]))
+		case c == '-':
+			b.WriteByte(c)
+		default:
+			b.WriteString(classLiteral(c))
+		}
+	}
+	return "", -1
+}
+
+// classLiteral Escapes ASCII punctuation (i.e. `[`, `-` or `\`) which can be
+// special in regexp classes
+func classLiteral(c byte) string {
+	isAlnum := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	if isAlnum || c >= utf8.RuneSelf || c <= ' ' {
+		return string([]byte{c})
+	}
+	return `\` + string(c)
+}
+
+func matchesAny(patterns []GlobPattern, rel string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(rel, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIncluded A file is included if it or any of its directories matches an include glob
+func isIncluded(includes []GlobPattern, rel string) bool {
+	if len(includes) == 0 || matchesAny(includes, rel, false) {
+		return true
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if matchesAny(includes, dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcluded A file is excluded if it or any of its directories matches an exclude glob
+func isExcluded(excludes []GlobPattern, rel string) bool {
+	if matchesAny(excludes, rel, false) {
+		return true
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if matchesAny(excludes, dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGitignored The last matching rule decides (negated rules re-include paths)
+func isGitignored(rules []GlobPattern, rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.Match(rel, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ReadGitignore Returns the rules of the .gitignore file in the directory rel of
+// fsys (if there is one)
+func ReadGitignore(fsys fs.FS, rel string) []GlobPattern {
+	file, err := fsys.Open(path.Join(rel, GitignoreFilename))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	if rel == "." {
+		rel = ""
+	}
+
+	rules := []GlobPattern{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := trimGitignoreSpaces(strings.TrimRight(scanner.Text(), "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, NewGlobPattern(line, rel))
+	}
+	return rules
+}
+
+// trimGitignoreSpaces Removes trailing spaces unless they are escaped
+func trimGitignoreSpaces(line string) string {
+	trimmed := strings.TrimRight(line, " ")
+	if len(trimmed) < len(line) && strings.HasSuffix(trimmed, "\\") {
+		// Escaped backslashes don't escape the space
+		backslashes := len(trimmed) - len(strings.TrimRight(trimmed, "\\"))
+		if backslashes%2 == 1 {
+			return trimmed + " "
+		}
+	}
+	return trimmed
+}
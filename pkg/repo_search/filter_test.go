@@ -0,0 +1,125 @@
+package repo_search
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestGlobPatternMatch(t *testing.T) {
+	tests := []struct {
+		glob  string
+		base  string
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"*.pyc", "", "a.pyc", false, true},
+		{"*.pyc", "", "lib/hal/a.pyc", false, true},
+		{"*.pyc", "", "lib/a.py", false, false},
+		{"/build", "", "build", true, true},
+		{"/build", "", "lib/build", true, false},
+		{"lib/*.py", "", "lib/a.py", false, true},
+		{"lib/*.py", "", "lib/hal/a.py", false, false},
+		{"lib/**/a.py", "", "lib/a.py", false, true},
+		{"lib/**/a.py", "", "lib/hal/sim/a.py", false, true},
+		{"lib/**", "", "lib/hal/a.py", false, true},
+		{"build/", "", "build", true, true},
+		{"build/", "", "build", false, false},
+		{"test_?.py", "", "test_1.py", false, true},
+		{"test_?.py", "", "test_12.py", false, false},
+		{"test_[0-9].py", "", "test_1.py", false, true},
+		{"test_[!0-9].py", "", "test_1.py", false, false},
+		{"*.log", "lib", "lib/hal/a.log", false, true},
+		{"*.log", "lib", "a.log", false, false},
+		{"/a.log", "lib", "lib/a.log", false, true},
+		{"/a.log", "lib", "lib/hal/a.log", false, false},
+		// Escapes
+		{`\*.py`, "", "*.py", false, true},
+		{`\*.py`, "", "a.py", false, false},
+		{`a\?.py`, "", "a?.py", false, true},
+		{`a\?.py`, "", "ab.py", false, false},
+		{`a\\b`, "", `a\b`, false, true},
+		{`\#notes`, "", "#notes", false, true},
+		{`lib\`, "", `lib\`, false, false},
+		{`test_[\]].py`, "", "test_].py", false, true},
+		{`test_[a\-z].py`, "", "test_-.py", false, true},
+		{`test_[a\-z].py`, "", "test_b.py", false, false},
+		// Brackets
+		{"test_[]a].py", "", "test_].py", false, true},
+		{"test_[]a].py", "", "test_a.py", false, true},
+		{"test_[[].py", "", "test_[.py", false, true},
+		{"test_[.py", "", "test_[.py", false, false},
+		{`test_[\].py`, "", `test_\.py`, false, false},
+		{"test_[z-a].py", "", "test_b.py", false, false},
+	}
+
+	for _, tt := range tests {
+		got := NewGlobPattern(tt.glob, tt.base).Match(tt.rel, tt.isDir)
+		if got != tt.want {
+			t.Errorf("glob %q (base %q) matches %q (dir %v) = %v, want %v", tt.glob, tt.base, tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeExclude(t *testing.T) {
+	includes := GlobPatterns([]string{"lib", "test_cases/heater/*.py"})
+	excludes := GlobPatterns([]string{"sim", "*_old.py"})
+
+	tests := []struct {
+		rel      string
+		included bool
+		excluded bool
+	}{
+		{"lib/hal/heater.py", true, false},
+		{"lib/hal/heater_old.py", true, true},
+		{"test_cases/heater/test_001.py", true, false},
+		{"test_cases/other/test_002.py", false, false},
+		{"lib/sim/heater.py", true, true},
+	}
+
+	for _, tt := range tests {
+		if got := isIncluded(includes, tt.rel); got != tt.included {
+			t.Errorf("isIncluded(%q) = %v, want %v", tt.rel, got, tt.included)
+		}
+		if got := isExcluded(excludes, tt.rel); got != tt.excluded {
+			t.Errorf("isExcluded(%q) = %v, want %v", tt.rel, got, tt.excluded)
+		}
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":     {Data: []byte("# generated\n*.log\nbuild/\n!keep.log\n\\!bang.py\nspace\\ \ntrailing  \n\n")},
+		"lib/.gitignore": {Data: []byte("/local.py\r\n")},
+	}
+	rules := append(ReadGitignore(fsys, "."), ReadGitignore(fsys, "lib")...)
+
+	tests := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"a.log", false, true},
+		{"lib/hal/a.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"lib/local.py", false, true},
+		{"lib/hal/local.py", false, false},
+		{"local.py", false, false},
+		{"!bang.py", false, true},
+		{"space ", false, true},
+		{"space", false, false},
+		{"trailing", false, true},
+	}
+
+	for _, tt := range tests {
+		if got := isGitignored(rules, tt.rel, tt.isDir); got != tt.ignored {
+			t.Errorf("isGitignored(%q, dir %v) = %v, want %v", tt.rel, tt.isDir, got, tt.ignored)
+		}
+	}
+
+	if rules := ReadGitignore(fsys, "test_cases"); len(rules) != 0 {
+		t.Errorf("expected no rules without a .gitignore, got %d", len(rules))
+	}
+}
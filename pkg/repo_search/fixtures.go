@@ -0,0 +1,340 @@
+package repo_search
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const ConftestFilename = "conftest.py"
+
+var (
+	pythonDefStartPattern     = regexp.MustCompile(`(?m)^[ \t]*(?:async[ \t]+)?def[ \t]+\w+[ \t]*\(`)
+	pythonUseFixturesPattern  = regexp.MustCompile(`usefixtures\(([^)]*)\)`)
+	pythonPluginsPattern      = regexp.MustCompile(`(?m)^pytest_plugins[ \t]*=[ \t]*(\([^)]*\)|\[[^\]]*\]|[^\n]*)`)
+	pythonQuotedModulePattern = regexp.MustCompile(`["']([\w.]+)["']`)
+)
+
+// FixtureScopeDir Fixtures from a conftest.py are available to all files in its
+// directory and below. Fixtures from any other module are only available in
+// that module (unless it is a plugin or the fixtures are imported).
+func (Python) FixtureScopeDir(definedIn string) (string, bool) {
+	if filepath.Base(definedIn) == ConftestFilename {
+		return filepath.Dir(definedIn), true
+	}
+	return "", false
+}
+
+// Plugins Modules listed in `pytest_plugins = [...]`
+func (Python) Plugins(text string) []string {
+	plugins := []string{}
+	for _, match := range pythonPluginsPattern.FindAllStringSubmatch(text, -1) {
+		for _, name := range pythonQuotedModulePattern.FindAllStringSubmatch(match[1], -1) {
+			plugins = append(plugins, name[1])
+		}
+	}
+	return plugins
+}
+
+// FixtureScopes Returns the paths that fixtures declared in definedIn can be
+// requested in. A path is either a file or a directory ("" for everywhere).
+// Fixtures imported from a module are available wherever the importer's are.
+func (r *Repo) FixtureScopes(definedIn string, fixture Scope) []string {
+	lang, ok := LanguageForFile(definedIn).(FixtureLanguage)
+	if !ok {
+		return nil
+	}
+	if dir, ok := lang.FixtureScopeDir(definedIn); ok {
+		return []string{dir}
+	}
+	if r.plugins[definedIn] {
+		return []string{""}
+	}
+
+	scopes := []string{definedIn}
+	for _, importer := range r.imports.NameImporters(definedIn, fixture.Name) {
+		if dir, ok := lang.FixtureScopeDir(importer); ok {
+			scopes = append(scopes, dir)
+		} else {
+			scopes = append(scopes, importer)
+		}
+	}
+	return scopes
+}
+
+// InFixtureScopes Returns the length of the (longest) scope path that contains
+// file. Closer scopes are longer and their fixtures win. ok is false if none
+// of the scopes contains file.
+func InFixtureScopes(file string, scopes []string) (scopeLen int, ok bool) {
+	scopeLen = -1
+	for _, scope := range scopes {
+		inScope := scope == "" || file == scope || strings.HasPrefix(file, scope+string(filepath.Separator))
+		if inScope && len(scope) > scopeLen {
+			scopeLen = len(scope)
+		}
+	}
+	return scopeLen, scopeLen != -1
+}
+
+func FixtureSearchKey(definedIn string, fixture Scope) string {
+	return fmt.Sprintf("fixture:%s:%s", definedIn, fixture.Name)
+}
+
+// SearchForFixtureUsages Finds the TCs that request fixture, either directly or
+// through other fixtures. Autouse fixtures apply to every TC in their scope.
+func SearchForFixtureUsages(
+	repo *Repo,
+	alreadySearched SearchedSet,
+	definedIn string,
+	fixture Scope,
+	degreesOfSeparation int,
+) TestCasesMap {
+	testCases := TestCasesMap{}
+	if degreesOfSeparation <= 0 {
+		return testCases
+	}
+
+	fixtureLang, ok := LanguageForFile(definedIn).(FixtureLanguage)
+	if !ok {
+		return testCases
+	}
+	scopes := repo.FixtureScopes(definedIn, fixture)
+	repo.traceTerm(Identifier(fixture.Name))
+	// Plugins and imports make fixtures available outside of their module
+	repo.traceModule(definedIn)
+	if fixture.Autouse {
+		repo.traceScopes(scopes...)
+	}
+
+	// Fixtures can only be requested from files of the same language. Files
+	// are searched in order so that TCs are always found through the same chain.
+	files := []string{}
+	texts := map[string]string{}
+	for _, file := range repo.files {
+		if _, ok := InFixtureScopes(file, scopes); !ok || LanguageForFile(file).Name() != fixtureLang.Name() {
+			continue
+		}
+
+		text, err := repo.ReadText(file)
+		if err != nil {
+			errorTxt := fmt.Sprintf("ERROR: Couldn't read file %s: %v", file, err)
+			log.Print(ErrorStyle.Render(errorTxt))
+			continue
+		}
+		files = append(files, file)
+		texts[file] = text
+	}
+	sort.Strings(files)
+
+	overrides := FixtureOverrideDirs(fixtureLang, texts, definedIn, fixture.Name)
+	searchTerm := fmt.Sprintf("%s (fixture)", fixture.Name)
+
+	for _, file := range files {
+		text := texts[file]
+		if isOverridden(fixtureLang, file, overrides) {
+			continue
+		}
+
+		lang := LanguageForFile(file)
+		isTc := lang.IsTcFile(file)
+		tcInfos := map[string]TestCaseInfo{}
+
+		if fixture.Autouse {
+			if !isTc {
+				continue
+			}
+			for _, info := range TestCasesAt(lang, text, file, 0, tcInfos) {
+				AddTcMatch(testCases, file, info, nil, searchTerm)
+			}
+			continue
+		}
+
+		lines := strings.Split(text, "\n")
+		requests := fixtureLang.FixtureRequests(text, fixture.Name)
+		if len(requests) > 0 {
+			repo.traceFiles(file)
+		}
+		for _, request := range requests {
+			match := ProcessMatch(request.match, text, lang, repo.Context)
+			match.file = file
+			match.relPath = repo.RelPath(file)
+			if isTc {
+				match.testCases = TestCasesAt(lang, text, file, request.match[0], tcInfos)
+			}
+			repo.record(searchTerm, match)
+
+			if isTc {
+				for _, info := range match.testCases {
+					AddTcMatch(testCases, file, info, &match, searchTerm)
+				}
+				continue
+			}
+
+			requester, isFixture := Scope{}, false
+			if request.defLine >= 0 {
+				requester, isFixture = fixtureLang.FixtureAt(lines, request.defLine)
+			}
+			if !isFixture {
+				warningTxt := fmt.Sprintf(
+					"Fixture %s requested outside of a TC or fixture:\n%s\n%d: %s",
+					fixture.Name,
+					file,
+					match.line,
+					match.matchLineTxt,
+				)
+				log.Println(WarningStyle.Render(warningTxt))
+				continue
+			}
+
+			fixtureKey := FixtureSearchKey(file, requester)
+			if _, ok := alreadySearched[fixtureKey]; ok {
+				continue
+			}
+
+			infoTxt := fmt.Sprintf("Extending search for fixture %s by fixture %s", fixture.Name, requester.Name)
+			log.Print(InfoStyle.Render(infoTxt))
+
+			alreadySearched[fixtureKey] = true
+			foundTcs := SearchForFixtureUsages(repo, alreadySearched, file, requester, degreesOfSeparation-1)
+			testCases = UpdateMap(testCases, ExtendChain(foundTcs, searchTerm))
+		}
+	}
+
+	return testCases
+}
+
+// FixtureOverrideDirs Returns the scope directories of the files (other than
+// definedIn, i.e. conftest files) that define a fixture with the same name.
+// Inside of them the fixture from definedIn is shadowed.
+func FixtureOverrideDirs(lang FixtureLanguage, texts map[string]string, definedIn, fixtureName string) []string {
+	dirs := []string{}
+	for file, text := range texts {
+		scopeDir, ok := lang.FixtureScopeDir(file)
+		if file == definedIn || !ok {
+			continue
+		}
+
+		lines := strings.Split(text, "\n")
+		for i := range lines {
+			if DeclarationNameAt(lang, lines, i) == "" {
+				continue
+			}
+			fixture, ok := lang.FixtureAt(lines, i)
+			if ok && fixture.Name == fixtureName {
+				dirs = append(dirs, scopeDir)
+				break
+			}
+		}
+	}
+	return dirs
+}
+
+// isOverridden The conftest that overrides a fixture is still searched cause the
+// overriding fixture can request the fixture it overrides.
+func isOverridden(lang FixtureLanguage, file string, overrideDirs []string) bool {
+	for _, dir := range overrideDirs {
+		if scopeDir, ok := lang.FixtureScopeDir(file); ok && scopeDir == dir {
+			continue
+		}
+		if strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+type FixtureRequest struct {
+	match []int
+	// Line index of the method declaration that requests the fixture
+	// (-1 for usefixtures markers)
+	defLine int
+}
+
+// FixtureRequests Finds parameters named fixtureName in method declarations and
+// fixtureName in usefixtures markers.
+func (Python) FixtureRequests(text, fixtureName string) []FixtureRequest {
+	requests := []FixtureRequest{}
+
+	for _, def := range pythonDefStartPattern.FindAllStringIndex(text, -1) {
+		paramsStart := def[1]
+		paramsEnd := matchingParen(text, paramsStart-1)
+		if paramsEnd == -1 {
+			continue
+		}
+
+		defLine := strings.Count(text[:def[0]], "\n")
+		offset := paramsStart
+		for _, param := range splitTopLevel(text[paramsStart:paramsEnd], ',') {
+			name := param
+			// Remove annotations and default values
+			if idx := strings.IndexAny(name, ":="); idx != -1 {
+				name = name[:idx]
+			}
+			name = strings.TrimLeft(strings.TrimSpace(name), "*")
+
+			if name == fixtureName {
+				start := offset + strings.Index(param, name)
+				requests = append(requests, FixtureRequest{
+					match:   []int{start, start + len(name)},
+					defLine: defLine,
+				})
+			}
+			offset += len(param) + 1
+		}
+	}
+
+	quotedName := regexp.MustCompile(fmt.Sprintf(`["']%s["']`, regexp.QuoteMeta(fixtureName)))
+	for _, marker := range pythonUseFixturesPattern.FindAllStringSubmatchIndex(text, -1) {
+		argsStart, argsEnd := marker[2], marker[3]
+		for _, quoted := range quotedName.FindAllStringIndex(text[argsStart:argsEnd], -1) {
+			// Skip the quotes
+			requests = append(requests, FixtureRequest{
+				match:   []int{argsStart + quoted[0] + 1, argsStart + quoted[1] - 1},
+				defLine: -1,
+			})
+		}
+	}
+
+	return requests
+}
+
+// matchingParen Returns index of the paren closing the one at text[open] or -1
+func matchingParen(text string, open int) int {
+	depth := 0
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel Splits s on sep but not inside of brackets
+func splitTopLevel(s string, sep byte) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
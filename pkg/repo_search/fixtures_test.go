@@ -0,0 +1,96 @@
+package repo_search
+
+import (
+	"io"
+	"testing"
+)
+
+func TestFixtureRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"parameter", "def test_001(heater):\n    pass\n", []string{"heater"}},
+		{"annotation and default", "def test_001(bench, heater: Heater = None):\n    pass\n", []string{"heater"}},
+		{"multi line parameters", "async def test_001(\n    bench,\n    heater,\n):\n    pass\n", []string{"heater"}},
+		{"usefixtures", "@pytest.mark.usefixtures(\"bench\", 'heater')\ndef test_001():\n    pass\n", []string{"heater"}},
+		{"other names", "def test_001(heater_off, my_heater):\n    heater()\n", nil},
+		{"default value call", "def test_001(bench=make(heater)):\n    pass\n", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := (Python{}).FixtureRequests(test.text, "heater")
+			got := []string{}
+			for _, request := range requests {
+				got = append(got, test.text[request.match[0]:request.match[1]])
+			}
+			if !equalStrings(got, test.want) {
+				t.Errorf("requests %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFixtureScoping(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"test_cases/heater/conftest.py": `import pytest
+
+@pytest.fixture
+def heater():
+    HEATER_IMPL.on()
+    return HEATER_IMPL
+
+@pytest.fixture
+def bench(heater):
+    return heater
+`,
+		// Requested directly, through another fixture and by a marker
+		"test_cases/heater/test_001_power.py": tcText("4AP2-1001", "def test_001_power(heater):\n    heater.power(5)\n"),
+		"test_cases/heater/test_002_bench.py": tcText("4AP2-1002", "def test_002_bench(bench):\n    pass\n"),
+		"test_cases/heater/test_003_marker.py": tcText("4AP2-1003",
+			"import pytest\n\n@pytest.mark.usefixtures(\"heater\")\ndef test_003_marker():\n    pass\n"),
+		// Overridden by a conftest closer to the TC
+		"test_cases/heater/mock/conftest.py":      "import pytest\n\n@pytest.fixture\ndef heater():\n    return None\n",
+		"test_cases/heater/mock/test_004_mock.py": tcText("4AP2-1004", "def test_004_mock(heater):\n    pass\n"),
+		// Overridden by a fixture that requests the one it overrides
+		"test_cases/heater/wrapped/conftest.py":         "import pytest\n\n@pytest.fixture\ndef heater(heater):\n    return heater\n",
+		"test_cases/heater/wrapped/test_005_wrapped.py": tcText("4AP2-1005", "def test_005_wrapped(heater):\n    pass\n"),
+		// Outside of the conftest's directory
+		"test_cases/cooler/test_006_cooler.py": tcText("4AP2-1006", "def test_006_cooler(heater):\n    pass\n"),
+		// Fixtures of a TC module only apply to that module
+		"test_cases/cooler/test_007_module.py": tcText("4AP2-1007",
+			"import pytest\n\n@pytest.fixture\ndef cooler():\n    HEATER_IMPL.off()\n\ndef test_007_module(cooler):\n    pass\n"),
+		"test_cases/cooler/test_008_other.py": tcText("4AP2-1008", "def test_008_other(cooler):\n    pass\n"),
+	})
+	repo.Output = io.Discard
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+	want := []string{"4AP2-1001", "4AP2-1002", "4AP2-1003", "4AP2-1005", "4AP2-1007"}
+	if got := sortedTcIds(testCases); !equalStrings(got, want) {
+		t.Errorf("TCs %q, want %q", got, want)
+	}
+}
+
+func TestAutouseFixture(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"test_cases/heater/conftest.py": `import pytest
+
+@pytest.fixture(autouse=True)
+def cleanup():
+    yield
+    HEATER_IMPL.off()
+`,
+		"test_cases/heater/test_001_power.py":     tcText("4AP2-1001", "def test_001_power():\n    pass\n"),
+		"test_cases/heater/sub/test_002_power.py": tcText("4AP2-1002", "def test_002_power(bench):\n    pass\n"),
+		"test_cases/cooler/test_003_cooler.py":    tcText("4AP2-1003", "def test_003_cooler():\n    pass\n"),
+	})
+	repo.Output = io.Discard
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+	want := []string{"4AP2-1001", "4AP2-1002"}
+	if got := sortedTcIds(testCases); !equalStrings(got, want) {
+		t.Errorf("TCs %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,225 @@
+package repo_search
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GitRoot Root that reads the files of dir (inside of a git repository) as they
+// are at revision rev instead of from the working tree
+func GitRoot(dir, rev string) (Root, error) {
+	fsys, err := NewGitFS(dir, rev)
+	if err != nil {
+		return Root{}, err
+	}
+	return Root{Dir: dir, FS: fsys}, nil
+}
+
+// GitFS Read only file system of the tree of a commit. File contents are read
+// on demand from a `git cat-file --batch` process.
+type GitFS struct {
+	dir    string
+	commit string
+	files  map[string]gitBlob
+	dirs   map[string][]fs.DirEntry
+
+	mu     sync.Mutex
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	cmd    *exec.Cmd
+}
+
+type gitBlob struct {
+	oid  string
+	size int64
+}
+
+func NewGitFS(dir, rev string) (*GitFS, error) {
+	out, err := gitOutput(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %s: %w", rev, err)
+	}
+
+	fsys := &GitFS{
+		dir:    dir,
+		commit: strings.TrimSpace(string(out)),
+		files:  map[string]gitBlob{},
+		dirs:   map[string][]fs.DirEntry{".": nil},
+	}
+
+	// Inside of a subdirectory of the repository ls-tree only lists that
+	// directory with paths relative to it
+	out, err = gitOutput(dir, "ls-tree", "-r", "-l", "-z", fsys.commit)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> SP <type> SP <object> SP <size> TAB <path>
+		meta, name, found := strings.Cut(entry, "\t")
+		fields := strings.Fields(meta)
+		// Skip submodules (commits) and symlinks
+		if !found || len(fields) != 4 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected ls-tree entry %q: %w", entry, err)
+		}
+		fsys.files[name] = gitBlob{oid: fields[2], size: size}
+		addToDir(fsys.dirs, name, fileInfo{name: path.Base(name), size: size})
+	}
+	sortDirs(fsys.dirs)
+	return fsys, nil
+}
+
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+func (g *GitFS) Open(name string) (fs.File, error) {
+	info, err := g.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &memDir{info: info.(fileInfo), entries: g.dirs[name]}, nil
+	}
+
+	data, err := g.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{info: info.(fileInfo), Reader: bytes.NewReader(data)}, nil
+}
+
+func (g *GitFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if blob, ok := g.files[name]; ok {
+		return fileInfo{name: path.Base(name), size: blob.size}, nil
+	}
+	if _, ok := g.dirs[name]; ok {
+		return fileInfo{name: path.Base(name), isDir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (g *GitFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := g.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry{}, entries...), nil
+}
+
+func (g *GitFS) ReadFile(name string) ([]byte, error) {
+	blob, ok := g.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cmd == nil {
+		if err := g.startCatFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := g.catFile(blob.oid)
+	if err != nil {
+		// The rest of the answer (if any) would be read as the answer to the
+		// next request -> start over with a new process
+		g.killCatFile()
+		return nil, fmt.Errorf("couldn't read %s from cat-file: %w", name, err)
+	}
+	return data, nil
+}
+
+// catFile Requests the blob with oid from the cat-file process
+func (g *GitFS) catFile(oid string) ([]byte, error) {
+	if _, err := fmt.Fprintln(g.stdin, oid); err != nil {
+		return nil, err
+	}
+	// <oid> SP <type> SP <size> LF <contents> LF
+	header, err := g.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 || fields[1] != "blob" {
+		return nil, fmt.Errorf("unexpected header %q", header)
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected header %q: %w", header, err)
+	}
+
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(g.stdout, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+func (g *GitFS) startCatFile() error {
+	cmd := exec.Command("git", "-C", g.dir, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	g.cmd = cmd
+	g.stdin = stdin
+	g.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+// killCatFile Stops the cat-file process without waiting for the rest of its output
+func (g *GitFS) killCatFile() {
+	if g.cmd == nil {
+		return
+	}
+	g.stdin.Close()
+	g.cmd.Process.Kill()
+	g.cmd.Wait()
+	g.cmd = nil
+}
+
+// Close Stops the cat-file process (if it was started)
+func (g *GitFS) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cmd == nil {
+		return nil
+	}
+	g.stdin.Close()
+	err := g.cmd.Wait()
+	g.cmd = nil
+	return err
+}
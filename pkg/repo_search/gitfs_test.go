@@ -0,0 +1,97 @@
+package repo_search
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitRepo Creates a repository with files committed to HEAD
+func newGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "files"},
+	} {
+		if _, err := gitOutput(dir, args...); err != nil {
+			t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+		}
+	}
+	return dir
+}
+
+func TestGitFSReadFile(t *testing.T) {
+	dir := newGitRepo(t, map[string]string{
+		"lib/heater.py": "def power(v):\n    pass\n",
+		"README.md":     "heater\n",
+	})
+	// Changes of the working tree are not visible at the revision
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := NewGitFS(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	for name, want := range map[string]string{"lib/heater.py": "def power(v):\n    pass\n", "README.md": "heater\n"} {
+		data, err := fsys.ReadFile(name)
+		if err != nil || string(data) != want {
+			t.Errorf("ReadFile(%s) = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := fsys.ReadFile("lib/cooler.py"); err == nil {
+		t.Error("expected an error for a file that is not in the revision")
+	}
+}
+
+func TestGitFSRecoversFromBadHeader(t *testing.T) {
+	dir := newGitRepo(t, map[string]string{
+		"lib/heater.py": "def power(v):\n    pass\n",
+		"README.md":     "heater\n",
+	})
+	fsys, err := NewGitFS(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	// A tree has a valid header of an unexpected type followed by contents
+	tree, err := gitOutput(dir, "rev-parse", "HEAD^{tree}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.files["tree"] = gitBlob{oid: strings.TrimSpace(string(tree)), size: 1}
+	fsys.files["missing"] = gitBlob{oid: strings.Repeat("0", 40), size: 1}
+
+	for _, name := range []string{"tree", "missing"} {
+		if _, err := fsys.ReadFile(name); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+		// Later reads are not affected by the rest of the bad answer
+		data, err := fsys.ReadFile("lib/heater.py")
+		if err != nil || string(data) != "def power(v):\n    pass\n" {
+			t.Errorf("ReadFile after %s = %q, %v", name, data, err)
+		}
+	}
+}
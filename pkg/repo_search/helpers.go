@@ -2,19 +2,13 @@ package repo_search
 
 import (
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 )
 
-const (
-	MethodPatternStr = `def\s+(?P<name>.*?)\(`
-	ClassPatternStr  = `class\s+(?P<name>.*?):`
-)
-
 type ContainerType int
 
 const (
@@ -27,119 +21,102 @@ var ContainerName = map[ContainerType]string{
 	MethodContainer: "method",
 }
 
-func FindAllStringIndex[T SearchTerm](s string, pattern T) [][]int {
-	if p, ok := any(pattern).(*regexp.Regexp); ok {
-		return p.FindAllStringIndex(s, -1)
-	}
+func GetFilesFromRoot(root Root, filter FileFilter) ([]string, error) {
+	var files []string
 
-	sub, ok := any(pattern).(string)
-	if !ok {
-		errorTxt := fmt.Sprintf(
-			"Expected either a regexp.Regexp or a string but got neither: %v",
-			pattern,
-		)
-		log.Fatal(ErrorStyle.Render(errorTxt))
-	}
+	includes := GlobPatterns(filter.Include)
+	excludes := GlobPatterns(filter.Exclude)
+	// Rules of all visited .gitignore files. Each rule only applies below its own directory.
+	ignoreRules := []GlobPattern{}
 
-	results := make([][]int, 0)
-	subLen := len(sub)
-	currentIdx := 0
-	currentTxt := s
-	for {
-		idx := strings.Index(currentTxt, sub)
-		if idx == -1 {
-			break
+	err := fs.WalkDir(root.FS, ".", func(rel string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
 
-		// The result indexes have to be absolute and not only relative to the current text
-		results = append(results, []int{idx + currentIdx, idx + subLen + currentIdx})
-
-		currentTxt = currentTxt[idx+subLen:]
-		currentIdx += idx + subLen
-	}
-
-	return results
-}
-
-func MatchContainerName(t ContainerType, s string) string {
-	pattern := MethodPatternStr
-	if t == ClassContainer {
-		pattern = ClassPatternStr
-	}
+		if rel != "." && (matchesAny(excludes, rel, entry.IsDir()) || isGitignored(ignoreRules, rel, entry.IsDir())) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
 
-	searchPattern, err := regexp.Compile(pattern)
-	if err != nil {
-		errorTxt := fmt.Sprintf("Couldn't compile %s declaration regex: %v", ContainerName[t], err)
-		log.Fatal(ErrorStyle.Render(errorTxt))
-	}
-	nameIdx := searchPattern.SubexpIndex("name")
+		if entry.IsDir() {
+			if filter.UseGitignore {
+				ignoreRules = append(ignoreRules, ReadGitignore(root.FS, rel)...)
+			}
+			return nil
+		}
 
-	match := searchPattern.FindStringSubmatch(s)
-	if match == nil {
-		return ""
-	}
-	return match[nameIdx]
+		path := root.File(rel)
+		if HasFileType(path, filter.FileTypes) &&
+			isIncluded(includes, rel) &&
+			!LanguageForFile(path).IsGenerated(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
 }
 
-func GetContainingMethod(pretext string) string {
-	// Pre compile some method testing patters
-	testMethodPattern, err := regexp.Compile(`^test_(\d+)_`)
-	if err != nil {
-		errorTxt := fmt.Sprintf("Couldn't compile test method declaration regex: %v", err)
-		log.Fatal(ErrorStyle.Render(errorTxt))
-	}
-
-	// Search for closest method declaration above usage -> this is used to
-	// continue searching for usages incase the match does not occur inside a test case file
-	usedInMethod := ""
-	lines := strings.Split(pretext, "\n")
-	for i := len(lines) - 1; i >= 0; i-- {
-		textLine := lines[i]
-
-		// Don't consider empty lines or lines containing only whitespace
-		if len(strings.TrimSpace(textLine)) == 0 {
-			continue
-		}
+// GetListedFiles Filters the listed files (absolute or relative to any of the
+// roots) instead of walking the roots. .gitignore files are not considered.
+func GetListedFiles(roots []Root, listed []string, filter FileFilter) []string {
+	var files []string
 
-		// If we encounter a class decl first -> no method name found -> stop search
-		className := MatchContainerName(ClassContainer, textLine)
-		if className != "" {
-			break
+	includes := GlobPatterns(filter.Include)
+	excludes := GlobPatterns(filter.Exclude)
+
+	for _, listedFile := range listed {
+		path, rel := "", ""
+		for _, root := range roots {
+			if filepath.IsAbs(listedFile) {
+				if rootFile, ok := root.RootFile(listedFile); ok {
+					path, rel = listedFile, rootFile
+					break
+				}
+				continue
+			}
+
+			candidate := filepath.ToSlash(filepath.Clean(listedFile))
+			if _, err := fs.Stat(root.FS, candidate); err == nil {
+				path, rel = root.File(candidate), candidate
+				break
+			}
 		}
 
-		// If current line doesn't have a method name -> continue searching with the next one
-		methodName := MatchContainerName(MethodContainer, textLine)
-		if methodName == "" {
+		if path == "" {
+			warningTxt := fmt.Sprintf("Listed file %s is not in any of the searched dirs", listedFile)
+			log.Println(WarningStyle.Render(warningTxt))
 			continue
 		}
 
-		// Do not use any test case official method as a containing method
-		if testMethodPattern.MatchString(methodName) {
-			break
+		if HasFileType(path, filter.FileTypes) &&
+			isIncluded(includes, rel) &&
+			!isExcluded(excludes, rel) &&
+			!LanguageForFile(path).IsGenerated(path) {
+			files = append(files, path)
 		}
+	}
+	return files
+}
 
-		// Do not use __init__ as containg method cause we can't search for it
-		if methodName == "__init__" {
-			break
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
 		}
-
-		usedInMethod = methodName
-		break
 	}
-
-	return usedInMethod
+	return false
 }
 
-func GetFilesFromDir(root string, fileType string) ([]string, error) {
-	var files []string
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		// TODO: add blacklist to script args
-		if !info.IsDir() && strings.HasSuffix(path, fileType) && !strings.HasSuffix(path, "_pb2"+fileType) {
-			files = append(files, path)
+func HasFileType(path string, fileTypes []string) bool {
+	for _, fileType := range fileTypes {
+		if strings.HasSuffix(path, fileType) {
+			return true
 		}
-		return nil
-	})
-	return files, err
+	}
+	return false
 }
 
 func AddTimestampToFilename(filename, ext string) string {
@@ -0,0 +1,411 @@
+package repo_search
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	pythonFromImportPattern = regexp.MustCompile(
+		`(?m)^[ \t]*from[ \t]+(?P<module>[\w.]+)[ \t]+import[ \t]+(?P<names>\([^)]*\)|[^\n#;]+)`,
+	)
+	pythonImportPattern = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+(?P<names>[^\n#;]+)`)
+)
+
+// Import One name imported by an import statement
+type Import struct {
+	// Module Absolute dotted name of the imported module
+	Module string
+	// Name Imported attribute of the module ("" if the module itself is imported, "*" for star imports)
+	Name string
+	// Alias Name under which the import is available in the importing file
+	Alias string
+}
+
+// ImportIndex Imports of every python file in the repo
+type ImportIndex struct {
+	// rootNames Names of the root directories. Roots can be packages themselves
+	// (i.e. `lib` when searching `lib` and `test_cases`).
+	rootNames []string
+	modules   map[string]string // file -> dotted module name
+	files     map[string]string // dotted module name -> file
+	imports   map[string][]Import
+}
+
+// BuildImportIndex Module names are relative to the root of the file. Imports
+// that are relative to some other folder on the python path are resolved by
+// matching the end of the module name.
+func BuildImportIndex(roots []string, texts map[string]string) *ImportIndex {
+	index := &ImportIndex{
+		modules: map[string]string{},
+		files:   map[string]string{},
+		imports: map[string][]Import{},
+	}
+	for _, root := range roots {
+		index.rootNames = append(index.rootNames, filepath.Base(root))
+	}
+
+	for file := range texts {
+		lang, ok := LanguageForFile(file).(ModuleLanguage)
+		if !ok {
+			continue
+		}
+		module := lang.ModuleName(RootOf(roots, file), file)
+		index.modules[file] = module
+
+		// Same module in multiple roots or as source and stub -> like on the
+		// python path the first root wins and sources win over stubs
+		if other, ok := index.files[module]; ok && !precedes(roots, file, other) {
+			continue
+		}
+		index.files[module] = file
+	}
+
+	for file, text := range texts {
+		lang, ok := LanguageForFile(file).(ModuleLanguage)
+		if !ok {
+			continue
+		}
+		index.imports[file] = index.resolveImports(file, lang.Imports(text))
+	}
+	return index
+}
+
+// Module Returns the dotted module name of a python file in the repo
+func (idx *ImportIndex) Module(file string) string {
+	return idx.modules[file]
+}
+
+// Importers Returns the python files that import the module file (or any of its attributes)
+func (idx *ImportIndex) Importers(file string) []string {
+	module, ok := idx.modules[file]
+	if !ok {
+		return nil
+	}
+
+	importers := []string{}
+	for importer, imports := range idx.imports {
+		for _, imp := range imports {
+			if imp.Module == module {
+				importers = append(importers, importer)
+				break
+			}
+		}
+	}
+	sort.Strings(importers)
+	return importers
+}
+
+// NameImporters Returns the python files that import name (or everything)
+// from the module file
+func (idx *ImportIndex) NameImporters(file, name string) []string {
+	module, ok := idx.modules[file]
+	if !ok {
+		return nil
+	}
+
+	importers := []string{}
+	for importer, imports := range idx.imports {
+		for _, imp := range imports {
+			if imp.Module == module && (imp.Name == name || imp.Name == "*") {
+				importers = append(importers, importer)
+				break
+			}
+		}
+	}
+	sort.Strings(importers)
+	return importers
+}
+
+// UnresolvedImporters Returns the python files that import name (or the whole
+// module) from a module that is not in the repo but is named like the module
+// file (i.e. because the searched roots are not on the python path as expected)
+func (idx *ImportIndex) UnresolvedImporters(file, name string) []string {
+	module, ok := idx.modules[file]
+	if !ok {
+		return nil
+	}
+	moduleName := module[strings.LastIndex(module, ".")+1:]
+
+	importers := []string{}
+	for importer, imports := range idx.imports {
+		for _, imp := range imports {
+			if imp.Name != name && imp.Name != "" && imp.Name != "*" {
+				continue
+			}
+			if _, inRepo := idx.files[imp.Module]; inRepo {
+				continue
+			}
+			if imp.Module == moduleName || strings.HasSuffix(imp.Module, "."+moduleName) {
+				importers = append(importers, importer)
+				break
+			}
+		}
+	}
+	sort.Strings(importers)
+	return importers
+}
+
+// File Returns the file of a (resolved) module name ("" if not in the repo)
+func (idx *ImportIndex) File(module string) string {
+	return idx.files[module]
+}
+
+func precedes(roots []string, file, other string) bool {
+	fileRoot, otherRoot := rootIndex(roots, file), rootIndex(roots, other)
+	if fileRoot != otherRoot {
+		return fileRoot < otherRoot
+	}
+	if isStub, otherIsStub := isStubFile(file), isStubFile(other); isStub != otherIsStub {
+		return otherIsStub
+	}
+	return file < other
+}
+
+func isStubFile(file string) bool {
+	lang, ok := LanguageForFile(file).(ModuleLanguage)
+	return ok && lang.IsStub(file)
+}
+
+// ModuleName Module names are the paths relative to the root (packages are
+// named after their directory)
+func (Python) ModuleName(root, file string) string {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		rel = file
+	}
+	rel = strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))
+	rel = strings.TrimSuffix(rel, "/__init__")
+	return strings.ReplaceAll(rel, "/", ".")
+}
+
+// IsPackageInit The __init__ module of a package is named after the package
+func (Python) IsPackageInit(file string) bool {
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) == "__init__"
+}
+
+// IsStub Type stubs (.pyi) only declare what their module provides
+func (Python) IsStub(file string) bool {
+	return filepath.Ext(file) == ".pyi"
+}
+
+// ResolveModule Returns the repo module with the given (absolute) name or the
+// one whose name ends with it. Returns "" if the module is not in the repo.
+func (idx *ImportIndex) ResolveModule(name string) string {
+	if _, ok := idx.files[name]; ok {
+		return name
+	}
+
+	resolved := ""
+	for module := range idx.files {
+		if !strings.HasSuffix(module, "."+name) {
+			continue
+		}
+		// Prefer the module closest to the root
+		if resolved == "" || len(module) < len(resolved) {
+			resolved = module
+		}
+	}
+	if resolved != "" {
+		return resolved
+	}
+
+	// Modules of a root that is a package are imported with its name
+	for _, rootName := range idx.rootNames {
+		if strings.HasPrefix(name, rootName+".") {
+			if _, ok := idx.files[name[len(rootName)+1:]]; ok {
+				return name[len(rootName)+1:]
+			}
+		}
+	}
+	return ""
+}
+
+// Imports `import a.b` binds `a` and `from a import b` binds `b` (unless aliased)
+func (Python) Imports(text string) []Import {
+	imports := []Import{}
+
+	for _, match := range pythonImportPattern.FindAllStringSubmatch(text, -1) {
+		names := match[pythonImportPattern.SubexpIndex("names")]
+		for _, name := range strings.Split(names, ",") {
+			module, alias := splitImportAlias(name)
+			if module == "" {
+				continue
+			}
+			if alias == "" {
+				alias = strings.Split(module, ".")[0]
+			}
+			imports = append(imports, Import{Module: module, Alias: alias})
+		}
+	}
+
+	for _, match := range pythonFromImportPattern.FindAllStringSubmatch(text, -1) {
+		module := match[pythonFromImportPattern.SubexpIndex("module")]
+		names := match[pythonFromImportPattern.SubexpIndex("names")]
+		names = strings.Trim(names, "()")
+
+		for _, name := range strings.Split(names, ",") {
+			name, alias := splitImportAlias(strings.TrimRight(name, "\\"))
+			if name == "" {
+				continue
+			}
+			if alias == "" {
+				alias = name
+			}
+			imports = append(imports, Import{Module: module, Name: name, Alias: alias})
+		}
+	}
+
+	return imports
+}
+
+func (Python) IsImport(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "import ") || strings.HasPrefix(trimmed, "from ")
+}
+
+// resolveImports Replaces the module names of the imports of file with the
+// names of the repo modules they refer to
+func (idx *ImportIndex) resolveImports(file string, imports []Import) []Import {
+	resolved := []Import{}
+	for _, imp := range imports {
+		if imp.Name == "" {
+			imp.Module = idx.resolveOrKeep(imp.Module)
+			resolved = append(resolved, imp)
+			continue
+		}
+
+		module := idx.absoluteModule(file, imp.Module)
+		// `from package import module` imports a module and not an attribute
+		if submodule := idx.ResolveModule(joinModule(module, imp.Name)); submodule != "" {
+			resolved = append(resolved, Import{Module: submodule, Alias: imp.Alias})
+			continue
+		}
+		imp.Module = idx.resolveOrKeep(module)
+		resolved = append(resolved, imp)
+	}
+	return resolved
+}
+
+func (idx *ImportIndex) resolveOrKeep(module string) string {
+	if resolved := idx.ResolveModule(module); resolved != "" {
+		return resolved
+	}
+	return module
+}
+
+// absoluteModule Resolves relative imports (i.e. `from ..a import b`)
+func (idx *ImportIndex) absoluteModule(file, module string) string {
+	level := len(module) - len(strings.TrimLeft(module, "."))
+	if level == 0 {
+		return module
+	}
+
+	pkg := strings.Split(idx.modules[file], ".")
+	// Module of a package's __init__ is the package itself
+	if lang, ok := LanguageForFile(file).(ModuleLanguage); !ok || !lang.IsPackageInit(file) {
+		pkg = pkg[:len(pkg)-1]
+	}
+	if level-1 > len(pkg) {
+		return strings.TrimLeft(module, ".")
+	}
+	pkg = pkg[:len(pkg)-(level-1)]
+
+	return joinModule(strings.Join(pkg, "."), strings.TrimLeft(module, "."))
+}
+
+func joinModule(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	if name == "" {
+		return pkg
+	}
+	return pkg + "." + name
+}
+
+// splitImportAlias Splits `name as alias` into its parts
+func splitImportAlias(s string) (string, string) {
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 1:
+		return fields[0], ""
+	case len(fields) == 3 && fields[1] == "as":
+		return fields[0], fields[2]
+	}
+	return "", ""
+}
+
+type exportedName struct {
+	module string
+	name   string
+}
+
+// SymbolNames Returns for each python file the names under which the method
+// `name` declared in definedIn is available. Only files that import the
+// declaring module (directly or through re-exports) are included. If classes
+// is not nil, files that import only other names from the module are skipped.
+func (idx *ImportIndex) SymbolNames(name, definedIn string, classes []string) map[string][]string {
+	definingModule, ok := idx.modules[definedIn]
+	if !ok {
+		return nil
+	}
+
+	// Find every module that re-exports the name (possibly under an alias)
+	exported := []exportedName{{definingModule, name}}
+	seen := map[exportedName]bool{exported[0]: true}
+	for i := 0; i < len(exported); i++ {
+		current := exported[i]
+		for file, imports := range idx.imports {
+			for _, imp := range imports {
+				if imp.Module != current.module || (imp.Name != current.name && imp.Name != "*") {
+					continue
+				}
+
+				alias := imp.Alias
+				if imp.Name == "*" {
+					alias = current.name
+				}
+				reexport := exportedName{idx.modules[file], alias}
+				if !seen[reexport] {
+					seen[reexport] = true
+					exported = append(exported, reexport)
+				}
+			}
+		}
+	}
+
+	names := map[string][]string{}
+	addName := func(file, name string) {
+		if !containsString(names[file], name) {
+			names[file] = append(names[file], name)
+		}
+	}
+
+	for _, export := range exported {
+		if file, ok := idx.files[export.module]; ok {
+			addName(file, export.name)
+		}
+
+		for file, imports := range idx.imports {
+			for _, imp := range imports {
+				if imp.Module != export.module {
+					continue
+				}
+
+				switch {
+				case imp.Name == export.name:
+					addName(file, imp.Alias)
+				case classes == nil, imp.Name == "", imp.Name == "*", containsString(classes, imp.Name):
+					// Module itself or another attribute of it (i.e. a class the
+					// name is a method of) is imported -> look for the plain name
+					addName(file, export.name)
+				}
+			}
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,213 @@
+package repo_search
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Language Describes everything the search needs to know about the
+// files of a specific programming language.
+type Language interface {
+	Name() string
+	// Extensions File extensions (incl. the dot) handled by the language
+	Extensions() []string
+	// DeclarationName Returns the name of the container of type t
+	// declared on line or "" if line is not such a declaration
+	DeclarationName(t ContainerType, line string) string
+	// UsagePattern Returns the pattern that matches usages of a method called name
+	UsagePattern(name string) *regexp.Regexp
+	// EnclosingScope Returns the closest method that encloses the end of
+	// pretext and that can be searched for (empty scope if there is none).
+	// line is the whole line that pretext ends on.
+	EnclosingScope(pretext, line string) Scope
+	// IsGenerated Reports whether the file is generated and should not be searched
+	IsGenerated(path string) bool
+	// IsTcFile Reports whether the file is a test case script
+	IsTcFile(path string) bool
+	// TestCaseTexts Returns the text of each TC (in a TC file) that is affected
+	// by a match at position pos. Test case info is extracted from it.
+	TestCaseTexts(text string, pos int) []string
+}
+
+// ModuleLanguage Optional capability of languages whose files are modules that
+// import each other. Names declared in such files are only available in the
+// files that import them, which the import and class indexes keep track of.
+type ModuleLanguage interface {
+	Language
+	// ModuleName Returns the dotted name of the module file relative to root
+	ModuleName(root, file string) string
+	// Imports Returns the names imported by text. Module names are as written
+	// (relative ones start with dots).
+	Imports(text string) []Import
+	// IsImport Reports whether line is an import statement
+	IsImport(line string) bool
+	// Classes Returns the classes declared in text
+	Classes(text string) []ClassDeclaration
+	// ConstructorName Returns the name of the method that constructs instances
+	// of a class (usages of the constructor are instantiations of the class)
+	ConstructorName() string
+	// IsPackageInit Reports whether file is the module of its directory
+	// (relative imports in it are resolved from the package itself)
+	IsPackageInit(file string) bool
+	// IsStub Reports whether file only declares the interface of a module.
+	// Sources take precedence over stubs of the same module.
+	IsStub(file string) bool
+}
+
+// FixtureLanguage Optional capability of languages with (pytest like)
+// fixtures. Fixtures are used by requesting them and not by calling them.
+type FixtureLanguage interface {
+	Language
+	// FixtureAt Returns the fixture declared on lines[defIdx] (ok is false if
+	// the declaration is not a fixture)
+	FixtureAt(lines []string, defIdx int) (fixture Scope, ok bool)
+	// FixtureRequests Returns the places in text that request fixtureName
+	FixtureRequests(text, fixtureName string) []FixtureRequest
+	// FixtureScopeDir Returns the directory that fixtures declared in definedIn
+	// are available in. ok is false if they are only available in definedIn
+	// itself (and wherever it is loaded as a plugin or they are imported).
+	FixtureScopeDir(definedIn string) (dir string, ok bool)
+	// Plugins Returns the names of the modules that text loads as plugins.
+	// Their fixtures are available everywhere.
+	Plugins(text string) []string
+}
+
+// BlockLanguage Optional capability of languages that know where the body of
+// a declaration ends
+type BlockLanguage interface {
+	Language
+	// BlockEnd Returns the index of the last line of the body of the
+	// declaration on lines[defIdx]
+	BlockEnd(lines []string, defIdx int) int
+}
+
+// SectionLanguage Optional capability of languages whose files are split into
+// sections of which only some can contain method declarations
+type SectionLanguage interface {
+	Language
+	// IsDeclarationSection Reports whether lines[idx] is in a section that
+	// declares methods
+	IsDeclarationSection(lines []string, idx int) bool
+}
+
+// SuiteLanguage Optional capability of languages whose TC files contain
+// several TCs
+type SuiteLanguage interface {
+	Language
+	// TestCaseRanges Returns the [start, end) offsets of each TC in text and of
+	// the parts of text that belong to all of them (i.e. suite settings)
+	TestCaseRanges(text string) (testCases, shared [][]int)
+}
+
+// DeclarationNameAt Returns the name of the method declared on lines[idx] or ""
+// if there is no declaration (or lines[idx] is in a section without them)
+func DeclarationNameAt(lang Language, lines []string, idx int) string {
+	if sectionLang, ok := lang.(SectionLanguage); ok && !sectionLang.IsDeclarationSection(lines, idx) {
+		return ""
+	}
+	return lang.DeclarationName(MethodContainer, lines[idx])
+}
+
+// constructorName Returns the name of the constructor of classes declared in
+// file ("" if its language has no classes)
+func constructorName(file string) string {
+	if lang, ok := LanguageForFile(file).(ModuleLanguage); ok {
+		return lang.ConstructorName()
+	}
+	return ""
+}
+
+type ScopeKind int
+
+const (
+	MethodScope ScopeKind = iota
+	// FixtureScope pytest fixture, its usages are parameters of the requesting tests/fixtures
+	FixtureScope
+	// ModuleScope Module level statement, Name is the assigned name ("" if
+	// nothing is assigned). Such statements run whenever the module is imported.
+	ModuleScope
+	// ConstructorScope __init__ of a class, Name is the class name. Its usages
+	// are instantiations of the class.
+	ConstructorScope
+)
+
+// Scope Searchable container of a match (i.e. the method the match is in)
+type Scope struct {
+	Name string
+	Kind ScopeKind
+	// Autouse Only for fixtures -> the fixture applies to every TC in its scope
+	Autouse bool
+	// Class Name of the class the method is declared in ("" for functions)
+	Class string
+	// Keyword Name under which Robot Framework calls the method if it differs
+	// from Name (i.e. set by the @keyword decorator of python libraries)
+	Keyword string
+}
+
+// Languages Supported languages. Files that don't belong to any of them are
+// searched as plain text.
+var Languages = []Language{
+	Python{},
+	Cpp{},
+	Robot{},
+}
+
+func LanguageForFile(path string) Language {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, lang := range Languages {
+		for _, langExt := range lang.Extensions() {
+			if ext == langExt {
+				return lang
+			}
+		}
+	}
+	return PlainText{}
+}
+
+// Cache of compiled symbol usage patterns (key is language name + symbol)
+var usagePatterns sync.Map
+
+// SymbolPattern Returns the usage pattern of a symbol name in files of lang
+func SymbolPattern(lang Language, name string) *regexp.Regexp {
+	key := lang.Name() + ":" + name
+	if pattern, ok := usagePatterns.Load(key); ok {
+		return pattern.(*regexp.Regexp)
+	}
+
+	pattern := lang.UsagePattern(name)
+	usagePatterns.Store(key, pattern)
+	return pattern
+}
+
+// WordPattern Add word boudary to make sure we search for exact word matches
+func WordPattern(name string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(name)))
+}
+
+// CallPattern Matches calls of name (i.e. instantiations of a class)
+func CallPattern(name string) *regexp.Regexp {
+	key := "call:" + name
+	if pattern, ok := usagePatterns.Load(key); ok {
+		return pattern.(*regexp.Regexp)
+	}
+
+	pattern := regexp.MustCompile(fmt.Sprintf(`\b%s\s*\(`, regexp.QuoteMeta(name)))
+	usagePatterns.Store(key, pattern)
+	return pattern
+}
+
+// PlainText Fallback language for unsupported files. Matches in such files can't
+// be traced any further.
+type PlainText struct{}
+
+func (PlainText) Name() string                                     { return "text" }
+func (PlainText) Extensions() []string                             { return nil }
+func (PlainText) DeclarationName(_ ContainerType, _ string) string { return "" }
+func (PlainText) UsagePattern(name string) *regexp.Regexp          { return WordPattern(name) }
+func (PlainText) EnclosingScope(_, _ string) Scope                 { return Scope{} }
+func (PlainText) IsGenerated(_ string) bool                        { return false }
+func (PlainText) IsTcFile(_ string) bool                           { return false }
+func (PlainText) TestCaseTexts(_ string, _ int) []string           { return nil }
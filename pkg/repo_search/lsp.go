@@ -0,0 +1,329 @@
+package repo_search
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC error codes used by the language server protocol
+const (
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// Windows paths of file URIs start with a slash (i.e. /C:/repo/lib.py)
+var windowsUriPathPattern = regexp.MustCompile(`^/[A-Za-z]:`)
+
+// LanguageServer Answers hover and code lens requests of editors (over stdio)
+// with the TCs that reach the function at a position. Files are searched as
+// they are saved on disk.
+type LanguageServer struct {
+	Repo *Repo
+	// Degrees Levels of recursive search
+	Degrees int
+
+	in  *bufio.Reader
+	out io.Writer
+	// found Already found TCs (key is file + scope), cleared when files change
+	found    map[string]TestCasesMap
+	shutdown bool
+}
+
+func NewLanguageServer(repo *Repo, degrees int, in io.Reader, out io.Writer) *LanguageServer {
+	return &LanguageServer{
+		Repo:    repo,
+		Degrees: degrees,
+		in:      bufio.NewReader(in),
+		out:     out,
+		found:   map[string]TestCasesMap{},
+	}
+}
+
+type lspRequest struct {
+	Id     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspCommand struct {
+	Title   string `json:"title"`
+	Command string `json:"command"`
+}
+
+// lspCodeLensData Identifies the function of an unresolved lens
+type lspCodeLensData struct {
+	Uri  string `json:"uri"`
+	Line int    `json:"line"`
+}
+
+type lspCodeLens struct {
+	Range   lspRange         `json:"range"`
+	Command *lspCommand      `json:"command,omitempty"`
+	Data    *lspCodeLensData `json:"data,omitempty"`
+}
+
+type lspTextDocumentParams struct {
+	TextDocument struct {
+		Uri string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// Run Handles messages until the client sends exit. Returns an error if the
+// connection breaks or the client exits without shutting the server down.
+func (s *LanguageServer) Run() error {
+	for {
+		body, err := s.readMessage()
+		if err != nil {
+			return err
+		}
+
+		request := lspRequest{}
+		if err := json.Unmarshal(body, &request); err != nil {
+			errorTxt := fmt.Sprintf("ERROR: Couldn't parse message %q: %v", body, err)
+			log.Print(ErrorStyle.Render(errorTxt))
+			continue
+		}
+
+		if request.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(request)
+		// Notifications don't get a response
+		if request.Id == nil {
+			continue
+		}
+		if err := s.respond(*request.Id, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *LanguageServer) handle(request lspRequest) (any, *lspError) {
+	switch request.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"hoverProvider":    true,
+				"codeLensProvider": map[string]any{"resolveProvider": true},
+				// Only saves are of interest, files are read from disk
+				"textDocumentSync": map[string]any{"openClose": false, "change": 0, "save": true},
+			},
+			"serverInfo": map[string]any{"name": "used_in_tc"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didSave", "workspace/didChangeWatchedFiles":
+		if changed := s.Repo.Refresh(); len(changed) > 0 {
+			s.found = map[string]TestCasesMap{}
+		}
+		return nil, nil
+	case "textDocument/hover":
+		params := lspTextDocumentParams{}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		return s.hover(params), nil
+	case "textDocument/codeLens":
+		params := lspTextDocumentParams{}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		return s.codeLenses(params), nil
+	case "codeLens/resolve":
+		lens := lspCodeLens{}
+		if err := json.Unmarshal(request.Params, &lens); err != nil {
+			return nil, &lspError{lspInvalidParams, err.Error()}
+		}
+		return s.resolveCodeLens(lens), nil
+	}
+
+	if strings.HasPrefix(request.Method, "$/") || request.Id == nil {
+		// Optional notifications (i.e. initialized, $/cancelRequest) can be ignored
+		return nil, nil
+	}
+	return nil, &lspError{lspMethodNotFound, fmt.Sprintf("unsupported method %s", request.Method)}
+}
+
+// hover Returns the TCs that reach the function at the position (nil if there is none)
+func (s *LanguageServer) hover(params lspTextDocumentParams) any {
+	file, lines, ok := s.document(params.TextDocument.Uri)
+	if !ok || params.Position.Line >= len(lines) {
+		return nil
+	}
+
+	scope := s.scopeAt(file, lines, params.Position.Line)
+	if scope.Name == "" {
+		return nil
+	}
+	testCases := s.testCases(file, scope)
+
+	contents := []string{fmt.Sprintf("**%s** is used in %d TCs", scope.Name, len(testCases))}
+	for _, tc := range SortedTcs(testCases) {
+		contents = append(contents, fmt.Sprintf("- %s: %s", tc.info.id, formatChain(tc.chain)))
+	}
+	return map[string]any{
+		"contents": map[string]any{"kind": "markdown", "value": strings.Join(contents, "\n")},
+	}
+}
+
+// codeLenses Returns a lens for every searchable function of the document. The
+// TCs are only searched when the client resolves a lens (see resolveCodeLens).
+func (s *LanguageServer) codeLenses(params lspTextDocumentParams) any {
+	lenses := []lspCodeLens{}
+
+	file, lines, ok := s.document(params.TextDocument.Uri)
+	if !ok {
+		return lenses
+	}
+
+	lang := LanguageForFile(file)
+	for i, line := range lines {
+		if DeclarationNameAt(lang, lines, i) == "" {
+			continue
+		}
+		scope := s.scopeAt(file, lines, i)
+		if scope.Name == "" {
+			continue
+		}
+
+		lenses = append(lenses, lspCodeLens{
+			Range: lspRange{lspPosition{i, 0}, lspPosition{i, len(line)}},
+			Data:  &lspCodeLensData{Uri: params.TextDocument.Uri, Line: i},
+		})
+	}
+	return lenses
+}
+
+// resolveCodeLens Adds the "used in N TCs" command to a lens of codeLenses
+func (s *LanguageServer) resolveCodeLens(lens lspCodeLens) any {
+	title := "used in ? TCs"
+	if lens.Data != nil {
+		file, lines, ok := s.document(lens.Data.Uri)
+		if ok && lens.Data.Line < len(lines) {
+			if scope := s.scopeAt(file, lines, lens.Data.Line); scope.Name != "" {
+				title = fmt.Sprintf("used in %d TCs", len(s.testCases(file, scope)))
+			}
+		}
+	}
+
+	lens.Command = &lspCommand{Title: title}
+	return lens
+}
+
+// document Returns the repo file of uri and its lines
+func (s *LanguageServer) document(uri string) (string, []string, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return "", nil, false
+	}
+
+	path := parsed.Path
+	if windowsUriPathPattern.MatchString(path) {
+		path = path[1:]
+	}
+	file, ok := s.Repo.FindFile(filepath.FromSlash(path))
+	if !ok {
+		return "", nil, false
+	}
+
+	text, err := s.Repo.ReadText(file)
+	if err != nil {
+		return "", nil, false
+	}
+	return file, strings.Split(text, "\n"), true
+}
+
+// scopeAt Returns the scope that encloses line lineIdx (a declaration line
+// belongs to the declared function)
+func (s *LanguageServer) scopeAt(file string, lines []string, lineIdx int) Scope {
+	pretext := strings.Join(lines[:lineIdx+1], "\n")
+	return LanguageForFile(file).EnclosingScope(pretext, lines[lineIdx])
+}
+
+func (s *LanguageServer) testCases(file string, scope Scope) TestCasesMap {
+	key := fmt.Sprintf("%s:%s.%s:%d", file, scope.Class, scope.Name, scope.Kind)
+	if testCases, ok := s.found[key]; ok {
+		return testCases
+	}
+
+	testCases := SearchForScopeUsages(s.Repo, SearchedSet{}, file, scope, s.Degrees)
+	s.found[key] = testCases
+	return testCases
+}
+
+// readMessage Reads the body of the next message (after its headers)
+func (s *LanguageServer) readMessage() ([]byte, error) {
+	length := -1
+	for {
+		header, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		header = strings.TrimSpace(header)
+		if header == "" {
+			break
+		}
+
+		name, value, found := strings.Cut(header, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid content length %q: %w", value, err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without content length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *LanguageServer) respond(id json.RawMessage, result any, rpcErr *lspError) error {
+	response := map[string]any{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		response["error"] = rpcErr
+	} else {
+		response["result"] = result
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,166 @@
+package repo_search
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var lspTestTree = fstest.MapFS{
+	"lib/heater.py": {Data: []byte("def power(v):\n    HEATER_IMPL(v)\n\ndef unused():\n    pass\n")},
+	"test_cases/heater/test_001_power.py": {Data: []byte(`"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+from lib.heater import power
+
+def test_001_power():
+    power(10)
+`)},
+}
+
+const lspTestUri = "file:///repo/lib/heater.py"
+
+type lspTestResponse struct {
+	Id     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *lspError       `json:"error"`
+}
+
+// runLanguageServer Sends requests (method -> params, in order) to a server of
+// the test tree and returns the responses by id (ids are the request indexes)
+func runLanguageServer(t *testing.T, requests [][2]string) (map[int]lspTestResponse, error) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	in := bytes.Buffer{}
+	for i, request := range requests {
+		id := fmt.Sprintf(`"id":%d,`, i)
+		if request[0] == "exit" || request[0] == "initialized" {
+			id = ""
+		}
+		body := fmt.Sprintf(`{"jsonrpc":"2.0",%s"method":%q,"params":%s}`, id, request[0], request[1])
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	repo := NewRepo([]Root{{Dir: "/repo", FS: lspTestTree}}, FileFilter{FileTypes: []string{".py"}}, nil)
+	repo.Output = io.Discard
+	out := bytes.Buffer{}
+	runErr := NewLanguageServer(repo, 5, &in, &out).Run()
+
+	responses := map[int]lspTestResponse{}
+	reader := bufio.NewReader(&out)
+	server := &LanguageServer{in: reader}
+	for {
+		body, err := server.readMessage()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := lspTestResponse{}
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		responses[response.Id] = response
+	}
+	return responses, runErr
+}
+
+func TestLanguageServer(t *testing.T) {
+	document := fmt.Sprintf(`{"textDocument":{"uri":%q}}`, lspTestUri)
+	hover := func(line int) string {
+		return fmt.Sprintf(`{"textDocument":{"uri":%q},"position":{"line":%d,"character":4}}`, lspTestUri, line)
+	}
+	resolve := func(line int) string {
+		return fmt.Sprintf(`{"range":{"start":{"line":%d,"character":0},"end":{"line":%d,"character":13}},"data":{"uri":%q,"line":%d}}`,
+			line, line, lspTestUri, line)
+	}
+
+	responses, err := runLanguageServer(t, [][2]string{
+		{"initialize", `{}`},
+		{"initialized", `{}`},
+		{"textDocument/codeLens", document},
+		{"codeLens/resolve", resolve(0)},
+		{"codeLens/resolve", resolve(3)},
+		{"textDocument/hover", hover(1)},
+		{"textDocument/hover", hover(100)},
+		{"textDocument/hover", `{"textDocument":{"uri":"file:///repo/missing.py"},"position":{"line":0,"character":0}}`},
+		{"textDocument/definition", document},
+		{"textDocument/hover", `[]`},
+		{"shutdown", `null`},
+		{"exit", `null`},
+	})
+	if err != nil {
+		t.Fatalf("server stopped with %v", err)
+	}
+
+	initialize := struct {
+		Capabilities struct {
+			CodeLensProvider struct {
+				ResolveProvider bool `json:"resolveProvider"`
+			} `json:"codeLensProvider"`
+		} `json:"capabilities"`
+	}{}
+	if err := json.Unmarshal(responses[0].Result, &initialize); err != nil || !initialize.Capabilities.CodeLensProvider.ResolveProvider {
+		t.Errorf("initialize = %s, want a code lens resolve provider", responses[0].Result)
+	}
+
+	lenses := []lspCodeLens{}
+	if err := json.Unmarshal(responses[2].Result, &lenses); err != nil {
+		t.Fatal(err)
+	}
+	if len(lenses) != 2 || lenses[0].Data.Line != 0 || lenses[1].Data.Line != 3 {
+		t.Errorf("code lenses = %s, want unresolved lenses on lines 0 and 3", responses[2].Result)
+	}
+	for _, lens := range lenses {
+		if lens.Command != nil {
+			t.Errorf("code lens on line %d is already resolved", lens.Range.Start.Line)
+		}
+	}
+
+	for id, want := range map[int]string{3: "used in 1 TCs", 4: "used in 0 TCs"} {
+		lens := lspCodeLens{}
+		if err := json.Unmarshal(responses[id].Result, &lens); err != nil {
+			t.Fatal(err)
+		}
+		if lens.Command == nil || lens.Command.Title != want {
+			t.Errorf("resolved lens = %s, want %q", responses[id].Result, want)
+		}
+	}
+
+	if result := string(responses[5].Result); !strings.Contains(result, "**power** is used in 1 TCs") ||
+		!strings.Contains(result, "4AP2-1001") {
+		t.Errorf("hover in power = %s", result)
+	}
+	for _, id := range []int{6, 7} {
+		if result := string(responses[id].Result); result != "null" {
+			t.Errorf("hover %d = %s, want null", id, result)
+		}
+	}
+
+	if responses[8].Error == nil || responses[8].Error.Code != lspMethodNotFound {
+		t.Errorf("unsupported method error = %+v", responses[8].Error)
+	}
+	if responses[9].Error == nil || responses[9].Error.Code != lspInvalidParams {
+		t.Errorf("invalid params error = %+v", responses[9].Error)
+	}
+	if _, ok := responses[1]; ok {
+		t.Error("notification got a response")
+	}
+}
+
+func TestLanguageServerExitWithoutShutdown(t *testing.T) {
+	if _, err := runLanguageServer(t, [][2]string{{"initialize", `{}`}, {"exit", `null`}}); err == nil {
+		t.Error("expected an error for exit without shutdown")
+	}
+}
@@ -0,0 +1,183 @@
+package repo_search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Matcher Finds the matches of a search term in the text of a file. Library
+// users can provide their own implementations.
+type Matcher interface {
+	// FindAll Returns start and end index of every match in text (content of
+	// the file at path written in lang), in order of appearance
+	FindAll(text string, lang Language, path string) [][]int
+	// String Search term as shown in the logs and in the chain of found TCs
+	String() string
+}
+
+// MatcherKinds Matchers selectable from the CLI
+var MatcherKinds = []string{"literal", "regex", "icase", "word", "ident", "struct"}
+
+// NewMatcher Creates the matcher of kind (see MatcherKinds) for term
+func NewMatcher(kind, term string) (Matcher, error) {
+	switch kind {
+	case "literal":
+		return Literal(term), nil
+	case "regex":
+		pattern, err := regexp.Compile(term)
+		if err != nil {
+			return nil, err
+		}
+		return Regex{pattern}, nil
+	case "icase":
+		return NewCaseInsensitive(term), nil
+	case "word":
+		return NewWholeWord(term), nil
+	case "ident":
+		return Identifier(term), nil
+	case "struct":
+		return NewStructural(term)
+	}
+	return nil, fmt.Errorf("unknown matcher %q (expected one of %v)", kind, MatcherKinds)
+}
+
+// Literal Matches the exact text
+type Literal string
+
+func (l Literal) FindAll(text string, _ Language, _ string) [][]int {
+	sub := string(l)
+	if sub == "" {
+		return nil
+	}
+
+	results := make([][]int, 0)
+	subLen := len(sub)
+	currentIdx := 0
+	currentTxt := text
+	for {
+		idx := strings.Index(currentTxt, sub)
+		if idx == -1 {
+			break
+		}
+
+		// The result indexes have to be absolute and not only relative to the current text
+		results = append(results, []int{idx + currentIdx, idx + subLen + currentIdx})
+
+		currentTxt = currentTxt[idx+subLen:]
+		currentIdx += idx + subLen
+	}
+
+	return results
+}
+
+func (l Literal) String() string {
+	return string(l)
+}
+
+// Regex Matches a regular expression
+type Regex struct {
+	*regexp.Regexp
+}
+
+func (r Regex) FindAll(text string, _ Language, _ string) [][]int {
+	return r.FindAllStringIndex(text, -1)
+}
+
+// CaseInsensitive Matches the text ignoring case
+type CaseInsensitive struct {
+	Term    string
+	pattern *regexp.Regexp
+}
+
+func NewCaseInsensitive(term string) CaseInsensitive {
+	return CaseInsensitive{
+		Term:    term,
+		pattern: regexp.MustCompile("(?i)" + regexp.QuoteMeta(term)),
+	}
+}
+
+func (c CaseInsensitive) FindAll(text string, _ Language, _ string) [][]int {
+	return c.pattern.FindAllStringIndex(text, -1)
+}
+
+func (c CaseInsensitive) String() string {
+	return c.Term
+}
+
+// WholeWord Matches the text only between word boundaries (\b)
+type WholeWord struct {
+	Term    string
+	pattern *regexp.Regexp
+}
+
+func NewWholeWord(term string) WholeWord {
+	return WholeWord{Term: term, pattern: WordPattern(term)}
+}
+
+func (w WholeWord) FindAll(text string, _ Language, _ string) [][]int {
+	return w.pattern.FindAllStringIndex(text, -1)
+}
+
+func (w WholeWord) String() string {
+	return w.Term
+}
+
+// Identifier Matches the text only if it is not part of a longer identifier.
+// Unlike WholeWord the term can contain dots (i.e. `self.heater.power`) and
+// start or end with punctuation (i.e. `.power(`).
+type Identifier string
+
+func (i Identifier) FindAll(text string, lang Language, path string) [][]int {
+	term := string(i)
+	if term == "" {
+		return nil
+	}
+
+	// Identifier chars are only a problem where the term itself has one
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+
+	results := [][]int{}
+	for _, match := range Literal(term).FindAll(text, lang, path) {
+		before, _ := utf8.DecodeLastRuneInString(text[:match[0]])
+		after, _ := utf8.DecodeRuneInString(text[match[1]:])
+		if isIdentifierRune(first) && match[0] > 0 && isIdentifierRune(before) {
+			continue
+		}
+		if isIdentifierRune(last) && match[1] < len(text) && isIdentifierRune(after) {
+			continue
+		}
+		results = append(results, match)
+	}
+	return results
+}
+
+func (i Identifier) String() string {
+	return string(i)
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// FindAll Uses the names of the symbol in path (i.e. import aliases) and the
+// usage pattern of lang
+func (s Symbol) FindAll(text string, lang Language, path string) [][]int {
+	results := [][]int{}
+	for _, name := range s.NamesIn(path) {
+		pattern := SymbolPattern(lang, name)
+		if s.Kind == ConstructorScope {
+			pattern = CallPattern(name)
+		}
+		results = append(results, pattern.FindAllStringIndex(text, -1)...)
+	}
+	// Matches of different names (aliases) have to be in order of appearance
+	sort.Slice(results, func(i, j int) bool {
+		return results[i][0] < results[j][0]
+	})
+	return results
+}
@@ -0,0 +1,96 @@
+package repo_search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	text := "heater.power(1)\nHEATER.Power(2)\nself.heater.power_off()\nmy_heater.power(3)\n"
+
+	tests := []struct {
+		kind string
+		term string
+		// want Matched text prefixed with its line
+		want []string
+	}{
+		{"literal", "heater.power", []string{"1:heater.power", "3:heater.power", "4:heater.power"}},
+		{"literal", "", []string{}},
+		{"regex", `power\(\d\)`, []string{"1:power(1)", "4:power(3)"}},
+		{"icase", "heater.power", []string{"1:heater.power", "2:HEATER.Power", "3:heater.power", "4:heater.power"}},
+		{"word", "power", []string{"1:power", "4:power"}},
+		{"ident", "heater.power", []string{"1:heater.power"}},
+		{"ident", ".power(", []string{"1:.power(", "4:.power("}},
+		{"ident", "", []string{}},
+	}
+
+	for _, test := range tests {
+		matcher, err := NewMatcher(test.kind, test.term)
+		if err != nil {
+			t.Fatalf("%s %q: %v", test.kind, test.term, err)
+		}
+		if matcher.String() != test.term {
+			t.Errorf("%s %q: String() = %q", test.kind, test.term, matcher.String())
+		}
+
+		got := []string{}
+		for _, match := range matcher.FindAll(text, Python{}, "lib/heater.py") {
+			line := strings.Count(text[:match[0]], "\n") + 1
+			got = append(got, fmt.Sprintf("%d:%s", line, text[match[0]:match[1]]))
+		}
+		if !equalStrings(got, test.want) {
+			t.Errorf("%s %q: matches %q, want %q", test.kind, test.term, got, test.want)
+		}
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	for _, kind := range []string{"glob", ""} {
+		if _, err := NewMatcher(kind, "power"); err == nil {
+			t.Errorf("expected an error for matcher kind %q", kind)
+		}
+	}
+	if _, err := NewMatcher("regex", "power("); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
+
+// dirMatcher Custom matcher that only matches Term in the files below Dir
+type dirMatcher struct {
+	Term string
+	Dir  string
+}
+
+func (m dirMatcher) FindAll(text string, lang Language, path string) [][]int {
+	if !strings.HasPrefix(path, m.Dir+"/") {
+		return nil
+	}
+	return Literal(m.Term).FindAll(text, lang, path)
+}
+
+func (m dirMatcher) String() string {
+	return m.Term + " in " + m.Dir
+}
+
+func TestCustomMatcher(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.py":     "def power(v):\n    HEATER_IMPL(v)\n",
+		"lib/sim/heater.py": "def off():\n    HEATER_IMPL(0)\n",
+		"test_cases/heater/test_001_power.py": tcText("4AP2-1001",
+			"from lib.heater import power\n\ndef test_001_power():\n    power(5)\n"),
+		"test_cases/heater/test_002_off.py": tcText("4AP2-1002",
+			"from lib.sim.heater import off\n\ndef test_002_off():\n    off()\n"),
+	})
+	repo.Output = io.Discard
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, dirMatcher{"HEATER_IMPL", "repo/lib/sim"}, 3)
+	if got := sortedTcIds(testCases); !equalStrings(got, []string{"4AP2-1002"}) {
+		t.Errorf("TCs %q, want [4AP2-1002]", got)
+	}
+	want := []string{"HEATER_IMPL in repo/lib/sim", "off"}
+	if chain := testCases["4AP2-1002"].chain; !equalStrings(chain, want) {
+		t.Errorf("chain %q, want %q", chain, want)
+	}
+}
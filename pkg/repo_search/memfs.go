@@ -0,0 +1,146 @@
+package repo_search
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// archiveFS Read only file system of the files of an archive, kept in memory
+type archiveFS struct {
+	files map[string][]byte
+	dirs  map[string][]fs.DirEntry
+}
+
+func newArchiveFS() *archiveFS {
+	return &archiveFS{
+		files: map[string][]byte{},
+		dirs:  map[string][]fs.DirEntry{".": nil},
+	}
+}
+
+// add Adds a file, entries of directories have to be sorted afterwards (see sortDirs)
+func (a *archiveFS) add(name string, data []byte) {
+	if _, ok := a.files[name]; !ok {
+		addToDir(a.dirs, name, fileInfo{name: path.Base(name), size: int64(len(data))})
+	}
+	a.files[name] = data
+}
+
+func (a *archiveFS) Open(name string) (fs.File, error) {
+	info, err := a.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &memDir{info: info.(fileInfo), entries: a.dirs[name]}, nil
+	}
+	return &memFile{info: info.(fileInfo), Reader: bytes.NewReader(a.files[name])}, nil
+}
+
+func (a *archiveFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := a.files[name]; ok {
+		return fileInfo{name: path.Base(name), size: int64(len(data))}, nil
+	}
+	if _, ok := a.dirs[name]; ok {
+		return fileInfo{name: path.Base(name), isDir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (a *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := a.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry{}, entries...), nil
+}
+
+func (a *archiveFS) ReadFile(name string) ([]byte, error) {
+	data, ok := a.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte{}, data...), nil
+}
+
+// addToDir Adds the entry for name to its directory (and the directories to their parents)
+func addToDir(dirs map[string][]fs.DirEntry, name string, info fileInfo) {
+	dir := path.Dir(name)
+	_, known := dirs[dir]
+	dirs[dir] = append(dirs[dir], fs.FileInfoToDirEntry(info))
+	if !known && dir != "." {
+		addToDir(dirs, dir, fileInfo{name: path.Base(dir), isDir: true})
+	}
+}
+
+// sortDirs Sorts the entries of each directory by name (as fs.ReadDir does)
+func sortDirs(dirs map[string][]fs.DirEntry) {
+	for _, entries := range dirs {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+}
+
+type fileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.isDir }
+func (i fileInfo) Sys() any           { return nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type memFile struct {
+	info fileInfo
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,73 @@
+package repo_search
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestArchiveFS(t *testing.T) {
+	fsys := newArchiveFS()
+	fsys.add("test_cases/heater/test_001.py", []byte("heater.power(10)\n"))
+	fsys.add("lib/hal/heater.py", []byte("def power(v):\n    pass\n"))
+	fsys.add("lib/conftest.py", nil)
+	sortDirs(fsys.dirs)
+
+	if err := fstest.TestFS(fsys, "test_cases/heater/test_001.py", "lib/hal/heater.py", "lib/conftest.py"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTar Writes a tar archive with a file per name to the test's temp dir
+func writeTar(t *testing.T, names ...string) string {
+	t.Helper()
+	tarPath := filepath.Join(t.TempDir(), "snapshot.tar")
+	file, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	w := tar.NewWriter(file)
+	for _, name := range names {
+		data := []byte("def power(v):\n    pass\n")
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tarPath
+}
+
+func TestReadTar(t *testing.T) {
+	root, err := OpenRoot(writeTar(t, "./lib/heater.py", "lib/hal/../pump.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	if err := fstest.TestFS(root.FS, "lib/heater.py", "lib/pump.py"); err != nil {
+		t.Error(err)
+	}
+	if !root.IsSnapshot() {
+		t.Error("expected a tar root to be a snapshot")
+	}
+}
+
+func TestReadTarRejectsInvalidNames(t *testing.T) {
+	for _, name := range []string{"../heater.py", "lib/../../heater.py", "/etc/heater.py"} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := OpenRoot(writeTar(t, "lib/pump.py", name)); err == nil {
+				t.Errorf("expected an error for %s", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package repo_search
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const vlProtocolsEnd = "</protocols>"
+
+type ProtocolXml struct {
+	XMLName xml.Name `xml:"protocol"`
+	Id      string   `xml:"id,attr"`
+}
+
+type DvPlanXml struct {
+	XMLName   xml.Name       `xml:"dv-plan"`
+	Protocols []*ProtocolXml `xml:"protocols>protocol"`
+}
+
+type TaToolExportXml struct {
+	XMLName xml.Name     `xml:"ta-tool-export"`
+	Plans   []*DvPlanXml `xml:"dv-plan"`
+}
+
+// VlExport Existing verification loop export (ta-tool-export xml) that found
+// TCs are added to
+type VlExport struct {
+	Path string
+	Text string
+	// ProtocolIds IDs of the protocols already in the export
+	ProtocolIds map[string]bool
+}
+
+func LoadVlExport(path string) (*VlExport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var exportXml TaToolExportXml
+	if err := xml.Unmarshal(data, &exportXml); err != nil {
+		return nil, fmt.Errorf("invalid ta-tool-export %s: %w", path, err)
+	}
+
+	text := string(data)
+	if !strings.Contains(text, vlProtocolsEnd) {
+		return nil, fmt.Errorf("ta-tool-export %s has no protocols section", path)
+	}
+
+	ids := map[string]bool{}
+	for _, plan := range exportXml.Plans {
+		for _, protocol := range plan.Protocols {
+			ids[protocol.Id] = true
+		}
+	}
+	return &VlExport{Path: path, Text: text, ProtocolIds: ids}, nil
+}
+
+// VlMerge Export with the found TCs added to it
+type VlMerge struct {
+	Text string
+	// Added IDs of the TCs that were added to the export
+	Added []string
+	// Planned IDs of the found TCs that were already in the export
+	Planned []string
+}
+
+// Merge Adds the protocols of the TCs that aren't in the export yet at the end
+// of its (last) protocols section. The rest of the export is kept as it is.
+func (e *VlExport) Merge(searchPattern string, testCases TestCasesMap, workItems WorkItems) VlMerge {
+	merge := VlMerge{Text: e.Text, Added: []string{}, Planned: []string{}}
+
+	newTcs := TestCasesMap{}
+	for id, tc := range testCases {
+		if e.ProtocolIds[id] {
+			merge.Planned = append(merge.Planned, id)
+		} else {
+			merge.Added = append(merge.Added, id)
+			newTcs[id] = tc
+		}
+	}
+	sort.Strings(merge.Added)
+	sort.Strings(merge.Planned)
+
+	if len(newTcs) == 0 {
+		return merge
+	}
+
+	protocols := fmt.Sprintf(SearchPatternTemplate, searchPattern) + CreateProtocolXml(newTcs, workItems)
+
+	// Insert before the indentation of the closing tag to keep it as it is
+	insertAt := strings.LastIndex(e.Text, vlProtocolsEnd)
+	lineStart := strings.LastIndex(e.Text[:insertAt], "\n") + 1
+	if strings.TrimSpace(e.Text[lineStart:insertAt]) == "" {
+		insertAt = lineStart
+	} else {
+		protocols = "\n" + protocols
+	}
+
+	merge.Text = e.Text[:insertAt] + protocols + e.Text[insertAt:]
+	return merge
+}
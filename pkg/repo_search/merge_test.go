@@ -0,0 +1,61 @@
+package repo_search
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVlExportMerge(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.py": "def power(v):\n    HEATER_IMPL(v)\n",
+		"test_cases/heater/test_001_power.py": `"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+from lib.heater import power
+
+def test_001_power():
+    power(5)
+`,
+		"test_cases/heater/test_002_off.py": `"""
+Polarion ID: 4AP2-1002
+Setup: Bench A
+"""
+from lib.heater import power
+
+def test_002_off():
+    power(0)
+`,
+	})
+	repo.Output = io.Discard
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+
+	export, err := LoadVlExport(filepath.Join("testdata", "vl_export.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge := export.Merge("HEATER_IMPL", testCases, nil)
+
+	if !equalStrings(merge.Added, []string{"4AP2-1002"}) {
+		t.Errorf("added %q, want [4AP2-1002]", merge.Added)
+	}
+	if !equalStrings(merge.Planned, []string{"4AP2-1001"}) {
+		t.Errorf("planned %q, want [4AP2-1001]", merge.Planned)
+	}
+
+	merged := filepath.Join(t.TempDir(), "merged.xml")
+	if err := os.WriteFile(merged, []byte(merge.Text), 0666); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, merged, "vl_merged.xml")
+}
+
+func TestVlExportMergeNothingNew(t *testing.T) {
+	export := &VlExport{Text: "<protocols>\n</protocols>\n", ProtocolIds: map[string]bool{"4AP2-1001": true}}
+	merge := export.Merge("HEATER_IMPL", TestCasesMap{"4AP2-1001": {}}, nil)
+	if merge.Text != export.Text || len(merge.Added) != 0 {
+		t.Errorf("expected the export to be kept as it is, added %q:\n%s", merge.Added, merge.Text)
+	}
+}
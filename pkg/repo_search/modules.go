@@ -0,0 +1,56 @@
+package repo_search
+
+import (
+	"fmt"
+	"log"
+)
+
+func ModuleSearchKey(file string) string {
+	return fmt.Sprintf("module:%s", file)
+}
+
+// SearchForModuleImporters Finds the TCs that import the module file, either
+// directly or through other modules. Module level statements of file (that
+// don't assign a name) run on every such import.
+func SearchForModuleImporters(repo *Repo, alreadySearched SearchedSet, file string, degreesOfSeparation int) TestCasesMap {
+	testCases := TestCasesMap{}
+	if degreesOfSeparation <= 0 {
+		return testCases
+	}
+	repo.traceModule(file)
+
+	searchTerm := fmt.Sprintf("%s (module)", repo.imports.Module(file))
+
+	for _, importer := range repo.imports.Importers(file) {
+		repo.traceFiles(importer)
+		lang := LanguageForFile(importer)
+		if lang.IsTcFile(importer) {
+			text, err := repo.ReadText(importer)
+			if err != nil {
+				errorTxt := fmt.Sprintf("ERROR: Couldn't read file %s: %v", importer, err)
+				log.Print(ErrorStyle.Render(errorTxt))
+				continue
+			}
+
+			tcInfos := map[string]TestCaseInfo{}
+			for _, info := range TestCasesAt(lang, text, importer, 0, tcInfos) {
+				AddTcMatch(testCases, importer, info, nil, searchTerm)
+			}
+			continue
+		}
+
+		moduleKey := ModuleSearchKey(importer)
+		if _, ok := alreadySearched[moduleKey]; ok {
+			continue
+		}
+
+		infoTxt := fmt.Sprintf("Extending search for module %s by importer %s", searchTerm, importer)
+		log.Print(InfoStyle.Render(infoTxt))
+
+		alreadySearched[moduleKey] = true
+		foundTcs := SearchForModuleImporters(repo, alreadySearched, importer, degreesOfSeparation-1)
+		testCases = UpdateMap(testCases, ExtendChain(foundTcs, searchTerm))
+	}
+
+	return testCases
+}
@@ -0,0 +1,68 @@
+package repo_search
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPythonModuleScope(t *testing.T) {
+	tests := []struct {
+		name string
+		// text Code before the match (marked by |) and the rest of its line
+		text string
+		want Scope
+	}{
+		{"constant", "HEATER = |HEATER_IMPL\n", Scope{Name: "HEATER", Kind: ModuleScope}},
+		{"annotated constant", "HEATER: int = |HEATER_IMPL\n", Scope{Name: "HEATER", Kind: ModuleScope}},
+		{"table entry", "POWER_TABLE = {\n    \"low\": 1,\n    \"high\": |HEATER_IMPL,\n}\n", Scope{Name: "POWER_TABLE", Kind: ModuleScope}},
+		{"object construction", "heater = Heater(\n    impl=|HEATER_IMPL,\n)\n", Scope{Name: "heater", Kind: ModuleScope}},
+		{"statement", "register(|HEATER_IMPL)\n", Scope{Kind: ModuleScope}},
+		{"after method", "def power():\n    pass\n\nHEATER = |HEATER_IMPL\n", Scope{Name: "HEATER", Kind: ModuleScope}},
+		{"after docstring", "\"\"\"\nHEATER = 1\n\"\"\"\nregister(|HEATER_IMPL)\n", Scope{Kind: ModuleScope}},
+		{"import", "from lib.heater import |HEATER_IMPL\n", Scope{}},
+		{"main block", "if __name__ == \"__main__\":\n    run(|HEATER_IMPL)\n", Scope{}},
+		{"in method", "def power():\n    return |HEATER_IMPL\n", Scope{Name: "power"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pos := strings.Index(test.text, "|")
+			text := test.text[:pos] + test.text[pos+1:]
+			lineStart := strings.LastIndex(text[:pos], "\n") + 1
+			line := text[lineStart : lineStart+strings.Index(text[lineStart:], "\n")]
+
+			if scope := (Python{}).EnclosingScope(text[:pos], line); scope != test.want {
+				t.Errorf("scope %+v, want %+v", scope, test.want)
+			}
+		})
+	}
+}
+
+func TestModuleLevelMatches(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/__init__.py": "",
+		"lib/config.py":   "POWER_TABLE = {\n    \"low\": 1,\n    \"high\": HEATER_IMPL,\n}\n",
+		"lib/setup.py":    "from lib.registry import register\n\nregister(HEATER_IMPL)\n",
+		"lib/bench.py":    "import lib.setup\n\ndef warm_up():\n    pass\n",
+		// Uses the assigned name
+		"test_cases/heater/test_001_table.py": tcText("4AP2-1001",
+			"from lib.config import POWER_TABLE\n\ndef test_001_table():\n    assert POWER_TABLE[\"high\"]\n"),
+		// Imports the module with the statement directly and through another module
+		"test_cases/heater/test_002_setup.py": tcText("4AP2-1002", "import lib.setup\n\ndef test_002_setup():\n    pass\n"),
+		"test_cases/heater/test_003_bench.py": tcText("4AP2-1003",
+			"from lib.bench import warm_up\n\ndef test_003_bench():\n    warm_up()\n"),
+		// Imports the module of the assignment but doesn't use the name
+		"test_cases/heater/test_004_other.py": tcText("4AP2-1004", "import lib.registry\n\ndef test_004_other():\n    pass\n"),
+	})
+	repo.Output = io.Discard
+
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+	want := []string{"4AP2-1001", "4AP2-1002", "4AP2-1003"}
+	if got := sortedTcIds(testCases); !equalStrings(got, want) {
+		t.Errorf("TCs %q, want %q", got, want)
+	}
+	if chain := testCases["4AP2-1001"].chain; !equalStrings(chain, []string{"HEATER_IMPL", "POWER_TABLE"}) {
+		t.Errorf("chain %q, want the assigned name", chain)
+	}
+}
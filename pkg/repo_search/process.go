@@ -3,73 +3,120 @@ package repo_search
 import (
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"strings"
 )
 
-func ProcessMatch(match []int, text string) SearchResult {
+// MatchContext Number of lines shown before and after each match
+type MatchContext struct {
+	Before int
+	After  int
+}
+
+// ProcessMatch Extracts the lines of a match (regex matches can span multiple
+// lines), the lines around them and the scope that contains the match
+func ProcessMatch(match []int, text string, lang Language, context MatchContext) SearchResult {
 	var (
 		start = match[0]
 		end   = match[1]
 
-		pretext  = text[:start]
-		posttext = text[end:]
+		pretext = text[:start]
 	)
 
-	line := strings.Count(pretext, "\n") + 1
-
-	leftNewLineIdx := strings.LastIndex(pretext, "\n")
-	rightNewLineIdx := strings.Index(posttext, "\n")
+	// Match lines start after the preceding newline (or at the start of the
+	// file) and end before the following one (or at the end of the file)
+	lineStart := strings.LastIndex(pretext, "\n") + 1
+	lineEnd := len(text)
+	if end > start && text[end-1] == '\n' {
+		// Match includes the newline of its last line
+		lineEnd = end - 1
+	} else if idx := strings.Index(text[end:], "\n"); idx != -1 {
+		lineEnd = end + idx
+	}
 
-	if leftNewLineIdx == -1 || rightNewLineIdx == -1 {
-		errorTxt := fmt.Sprintf(
-			"Couldn't find left newline or right newline for match %s: %d %d",
-			text[start:end],
-			leftNewLineIdx,
-			rightNewLineIdx,
-		)
-		log.Fatal(ErrorStyle.Render(errorTxt))
+	matchTxt := text[lineStart:lineEnd]
+	line := strings.Count(pretext, "\n") + 1
+	col := start - lineStart
+	colEnd := end - lineStart
+	if colEnd > len(matchTxt) {
+		colEnd = len(matchTxt)
 	}
 
-	// +1 is needed to ignore the preceding newline
-	matchTxt := (pretext[leftNewLineIdx+1:] +
-		text[start:end] +
-		posttext[:rightNewLineIdx])
-	col := start - leftNewLineIdx - 1
-	colEnd := end - leftNewLineIdx - 1
+	// Declarations and scopes are determined by the line the match starts on
+	firstLine, _, _ := strings.Cut(matchTxt, "\n")
+	lines := strings.Split(pretext, "\n")
+	lines[len(lines)-1] = firstLine
 
-	usedInMethod := ""
-	isMethodDecl := MatchContainerName(MethodContainer, matchTxt) != ""
-	// Only extract containing method if we don't have a method declaration in matchTxt
+	usedIn := Scope{}
+	isMethodDecl := IsDeclarationMatch(lang, lines, len(lines)-1, col, colEnd)
+	// Only extract containing method if the match is not the declared name.
+	// Matches after it (i.e. in a one line body) are usages inside of the method.
 	if !isMethodDecl {
-		usedInMethod = GetContainingMethod(pretext)
+		usedIn = lang.EnclosingScope(pretext, firstLine)
 	}
 
 	return SearchResult{
 		line:         line,
+		lineEnd:      line + strings.Count(matchTxt, "\n"),
 		col:          col,
 		colEnd:       colEnd,
 		matchLineTxt: matchTxt,
-		usedInMethod: usedInMethod,
+		before:       linesBefore(text, lineStart, context.Before),
+		after:        linesAfter(text, lineEnd, context.After),
+		usedIn:       usedIn,
 		isMethodDecl: isMethodDecl,
 	}
 }
 
-func IsPathTc(path string) bool {
-	sep := string(os.PathSeparator)
-	if sep != "/" {
-		sep = `\\` // on WIN make sure to escape the backslash
+// IsDeclarationMatch Reports whether the match from col to colEnd on
+// lines[idx] is the name of the method declared on that line
+func IsDeclarationMatch(lang Language, lines []string, idx, col, colEnd int) bool {
+	line := lines[idx]
+	name := strings.TrimSpace(DeclarationNameAt(lang, lines, idx))
+	if name == "" {
+		return false
 	}
-	testCasePathPattern := fmt.Sprintf(`test_cases%s.*?%stest_.*?\.py`, sep, sep)
 
-	tcPathPattern, err := regexp.Compile(testCasePathPattern)
-	if err != nil {
-		errorTxt := fmt.Sprintf("Couldn't compile TC path pattern: %v", err)
-		log.Fatal(ErrorStyle.Render(errorTxt))
+	// The declared name is followed by its parameters (if the language has any)
+	nameStart := -1
+	if span := CallPattern(name).FindStringIndex(line); span != nil {
+		nameStart = span[0]
+	} else {
+		nameStart = strings.Index(line, name)
+	}
+	if nameStart == -1 {
+		return true
+	}
+	return col < nameStart+len(name) && colEnd > nameStart
+}
+
+// linesBefore Returns up to n lines before the line that starts at lineStart
+func linesBefore(text string, lineStart, n int) []string {
+	lines := []string{}
+	// Index of the newline that ends the previous line
+	end := lineStart - 1
+	for len(lines) < n && end >= 0 {
+		start := strings.LastIndex(text[:end], "\n") + 1
+		lines = append([]string{text[start:end]}, lines...)
+		end = start - 1
+	}
+	return lines
+}
+
+// linesAfter Returns up to n lines after the line that ends at lineEnd
+func linesAfter(text string, lineEnd, n int) []string {
+	lines := []string{}
+	// A trailing newline doesn't start another line
+	start := lineEnd + 1
+	for len(lines) < n && start < len(text) {
+		end := len(text)
+		if idx := strings.Index(text[start:], "\n"); idx != -1 {
+			end = start + idx
+		}
+		lines = append(lines, text[start:end])
+		start = end + 1
 	}
-	isTc := tcPathPattern.FindString(path) != ""
-	return isTc
+	return lines
 }
 
 type TestCaseInfo struct {
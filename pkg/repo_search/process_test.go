@@ -0,0 +1,164 @@
+package repo_search
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestProcessMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		match   string
+		context MatchContext
+
+		line, lineEnd int
+		col, colEnd   int
+		matchLineTxt  string
+		before, after []string
+	}{
+		{
+			name:         "first line",
+			text:         "power(1)\nx = 2\n",
+			match:        "power",
+			line:         1,
+			lineEnd:      1,
+			col:          0,
+			colEnd:       5,
+			matchLineTxt: "power(1)",
+		},
+		{
+			name:         "last line without trailing newline",
+			text:         "x = 2\npower(1)",
+			match:        "power",
+			context:      MatchContext{Before: 3, After: 3},
+			line:         2,
+			lineEnd:      2,
+			col:          0,
+			colEnd:       5,
+			matchLineTxt: "power(1)",
+			before:       []string{"x = 2"},
+		},
+		{
+			name:         "match ends with the newline of its line",
+			text:         "a\npower(1)\nb\n",
+			match:        "(1)\n",
+			context:      MatchContext{After: 1},
+			line:         2,
+			lineEnd:      2,
+			col:          5,
+			colEnd:       8,
+			matchLineTxt: "power(1)",
+			after:        []string{"b"},
+		},
+		{
+			name:         "multi-line match",
+			text:         "a\npower(\n    1)\nb",
+			match:        "power(\n    1)",
+			context:      MatchContext{Before: 1, After: 1},
+			line:         2,
+			lineEnd:      3,
+			col:          0,
+			colEnd:       13,
+			matchLineTxt: "power(\n    1)",
+			before:       []string{"a"},
+			after:        []string{"b"},
+		},
+		{
+			name:         "context is limited by the file",
+			text:         "a\nb\npower\nc",
+			match:        "power",
+			context:      MatchContext{Before: 1, After: 5},
+			line:         3,
+			lineEnd:      3,
+			col:          0,
+			colEnd:       5,
+			matchLineTxt: "power",
+			before:       []string{"b"},
+			after:        []string{"c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(tt.text, tt.match)
+			if start == -1 {
+				t.Fatalf("%q not in text", tt.match)
+			}
+
+			result := ProcessMatch([]int{start, start + len(tt.match)}, tt.text, PlainText{}, tt.context)
+
+			if result.line != tt.line || result.lineEnd != tt.lineEnd {
+				t.Errorf("lines = %d-%d, want %d-%d", result.line, result.lineEnd, tt.line, tt.lineEnd)
+			}
+			if result.col != tt.col || result.colEnd != tt.colEnd {
+				t.Errorf("cols = %d-%d, want %d-%d", result.col, result.colEnd, tt.col, tt.colEnd)
+			}
+			if result.matchLineTxt != tt.matchLineTxt {
+				t.Errorf("matchLineTxt = %q, want %q", result.matchLineTxt, tt.matchLineTxt)
+			}
+			if !equalStrings(result.before, tt.before) {
+				t.Errorf("before = %q, want %q", result.before, tt.before)
+			}
+			if !equalStrings(result.after, tt.after) {
+				t.Errorf("after = %q, want %q", result.after, tt.after)
+			}
+		})
+	}
+}
+
+func TestProcessMatchDeclaration(t *testing.T) {
+	tests := []struct {
+		name         string
+		lang         Language
+		text         string
+		match        string
+		isMethodDecl bool
+		usedIn       string
+	}{
+		{"python declaration", Python{}, "def apply(v):\n    pass\n", "apply", true, ""},
+		{"python one line body", Python{}, "def power(v): return apply(v)\n", "apply", false, "power"},
+		{"c declaration", Cpp{}, "int apply(int v) {\n    return v;\n}\n", "apply", true, ""},
+		{"c one line body", Cpp{}, "int sim_power(int v) { return apply(v); }\n", "apply", false, "sim_power"},
+		{"c prototype", Cpp{}, "int apply(int v);\n", "apply", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(tt.text, tt.match)
+			result := ProcessMatch([]int{start, start + len(tt.match)}, tt.text, tt.lang, MatchContext{})
+
+			if result.isMethodDecl != tt.isMethodDecl {
+				t.Errorf("isMethodDecl = %v, want %v", result.isMethodDecl, tt.isMethodDecl)
+			}
+			if result.usedIn.Name != tt.usedIn {
+				t.Errorf("usedIn = %q, want %q", result.usedIn.Name, tt.usedIn)
+			}
+		})
+	}
+}
+
+func TestSearchFileCrlf(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/heater.py": {Data: []byte("def power(v):\r\n    apply(v)\r\n")},
+	}
+	repo := NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py"}}, nil)
+	repo.Context = MatchContext{Before: 1}
+
+	result := SearchFile(repo, "repo/lib/heater.py", Literal("apply"))
+	if result == nil || len(result.matches) != 1 {
+		t.Fatalf("expected one match, got %v", result)
+	}
+
+	match := result.matches[0]
+	if match.line != 2 || match.matchLineTxt != "    apply(v)" {
+		t.Errorf("match = %d: %q, want 2: %q", match.line, match.matchLineTxt, "    apply(v)")
+	}
+	if !equalStrings(match.before, []string{"def power(v):"}) {
+		t.Errorf("before = %q", match.before)
+	}
+	if match.usedIn.Name != "power" {
+		t.Errorf("usedIn = %q, want power", match.usedIn.Name)
+	}
+}
@@ -0,0 +1,327 @@
+package repo_search
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	MethodPatternStr = `def\s+(?P<name>.*?)\(`
+	ClassPatternStr  = `class\s+(?P<name>.*?):`
+)
+
+var (
+	pythonFixturePattern     = regexp.MustCompile(`@(?:pytest\.)?fixture\b(?:\((?P<args>[^)]*)\))?`)
+	pythonFixtureNamePattern = regexp.MustCompile(`\bname\s*=\s*["'](\w+)["']`)
+	pythonAutousePattern     = regexp.MustCompile(`\bautouse\s*=\s*True\b`)
+	// Robot Framework keyword with a custom name (`@keyword("Set Power")`)
+	pythonKeywordPattern    = regexp.MustCompile(`@(?:[\w.]+\.)?keyword\(\s*(?:name\s*=\s*)?["'](?P<name>[^"']+)["']`)
+	pythonAssignmentPattern = regexp.MustCompile(`^(?P<name>\w+)\s*(?::[^=]*)?=(?:[^=]|$)`)
+)
+
+type Python struct{}
+
+func (Python) Name() string {
+	return "python"
+}
+
+func (Python) Extensions() []string {
+	return []string{".py", ".pyi", ".pyx"}
+}
+
+func (Python) DeclarationName(t ContainerType, line string) string {
+	return MatchContainerName(t, line)
+}
+
+func MatchContainerName(t ContainerType, s string) string {
+	pattern := MethodPatternStr
+	if t == ClassContainer {
+		pattern = ClassPatternStr
+	}
+
+	searchPattern, err := regexp.Compile(pattern)
+	if err != nil {
+		errorTxt := fmt.Sprintf("Couldn't compile %s declaration regex: %v", ContainerName[t], err)
+		log.Fatal(ErrorStyle.Render(errorTxt))
+	}
+	nameIdx := searchPattern.SubexpIndex("name")
+
+	match := searchPattern.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	return match[nameIdx]
+}
+
+func (Python) UsagePattern(name string) *regexp.Regexp {
+	return WordPattern(name)
+}
+
+func (Python) EnclosingScope(pretext, line string) Scope {
+	// Pre compile some method testing patters
+	testMethodPattern, err := regexp.Compile(`^test_(\d+)_`)
+	if err != nil {
+		errorTxt := fmt.Sprintf("Couldn't compile test method declaration regex: %v", err)
+		log.Fatal(ErrorStyle.Render(errorTxt))
+	}
+
+	// Search for closest method declaration above usage -> this is used to
+	// continue searching for usages incase the match does not occur inside a test case file
+	usedIn := Scope{}
+	lines := strings.Split(pretext, "\n")
+	lines[len(lines)-1] = line
+	if statement, ok := PythonModuleStatement(lines); ok {
+		return statement
+	}
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		textLine := lines[i]
+
+		// Don't consider empty lines or lines containing only whitespace
+		if len(strings.TrimSpace(textLine)) == 0 {
+			continue
+		}
+
+		// If we encounter a class decl first -> no method name found -> stop search
+		className := MatchContainerName(ClassContainer, textLine)
+		if className != "" {
+			break
+		}
+
+		// If current line doesn't have a method name -> continue searching with the next one
+		methodName := MatchContainerName(MethodContainer, textLine)
+		if methodName == "" {
+			continue
+		}
+
+		// Do not use any test case official method as a containing method
+		if testMethodPattern.MatchString(methodName) {
+			break
+		}
+
+		// __init__ can't be searched for by name -> search for instantiations of its class
+		if methodName == "__init__" {
+			if className := PythonEnclosingClass(lines, i); className != "" {
+				usedIn = Scope{Name: className, Kind: ConstructorScope, Class: className}
+			}
+			break
+		}
+
+		usedIn = Scope{
+			Name:    methodName,
+			Class:   PythonEnclosingClass(lines, i),
+			Keyword: PythonKeywordName(lines, i),
+		}
+		if fixture, ok := PythonFixture(lines, i); ok {
+			usedIn = fixture
+		}
+		break
+	}
+
+	return usedIn
+}
+
+// PythonModuleStatement Returns the scope of the module level statement that
+// the last of lines belongs to. ok is false if the lines end inside of a class
+// or method declaration.
+func PythonModuleStatement(lines []string) (scope Scope, ok bool) {
+	inString := pythonStringLines(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		isBlank := trimmed == "" || strings.HasPrefix(trimmed, "#")
+		if isBlank || inString[i] || indentation(line) > 0 {
+			continue
+		}
+
+		isDecorator := strings.HasPrefix(trimmed, "@")
+		if isDecorator || MatchContainerName(MethodContainer, line) != "" || MatchContainerName(ClassContainer, line) != "" {
+			return Scope{}, false
+		}
+
+		// Imports are followed through the import index and the main block
+		// doesn't run on import -> nothing to search for
+		if strings.HasPrefix(trimmed, "import ") ||
+			strings.HasPrefix(trimmed, "from ") ||
+			strings.HasPrefix(trimmed, "if __name__") {
+			return Scope{}, true
+		}
+
+		scope = Scope{Kind: ModuleScope}
+		if match := pythonAssignmentPattern.FindStringSubmatch(line); match != nil {
+			scope.Name = match[pythonAssignmentPattern.SubexpIndex("name")]
+		}
+		return scope, true
+	}
+
+	return Scope{}, false
+}
+
+// pythonStringLines Reports for each line whether it starts inside of a triple
+// quoted string (i.e. a docstring)
+func pythonStringLines(lines []string) []bool {
+	inString := make([]bool, len(lines))
+	quote := ""
+	for i, line := range lines {
+		inString[i] = quote != ""
+		for len(line) > 0 {
+			if quote == "" {
+				idx := strings.Index(line, `"""`)
+				if single := strings.Index(line, "'''"); single != -1 && (idx == -1 || single < idx) {
+					idx = single
+				}
+				if idx == -1 {
+					break
+				}
+				quote = line[idx : idx+3]
+				line = line[idx+3:]
+				continue
+			}
+
+			idx := strings.Index(line, quote)
+			if idx == -1 {
+				break
+			}
+			quote = ""
+			line = line[idx+3:]
+		}
+	}
+	return inString
+}
+
+// PythonEnclosingClass Returns the name of the class that the method declared
+// on lines[defIdx] belongs to or "" if it is a function
+func PythonEnclosingClass(lines []string, defIdx int) string {
+	defIndent := indentation(lines[defIdx])
+	for i := defIdx - 1; i >= 0 && defIndent > 0; i-- {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || indentation(line) >= defIndent {
+			continue
+		}
+
+		// First less indented declaration is either the class or an enclosing
+		// function (for nested functions)
+		if className := MatchContainerName(ClassContainer, line); className != "" {
+			return strings.TrimSpace(strings.Split(className, "(")[0])
+		}
+		if MatchContainerName(MethodContainer, line) != "" {
+			return ""
+		}
+		defIndent = indentation(line)
+	}
+	return ""
+}
+
+func (Python) BlockEnd(lines []string, defIdx int) int {
+	return PythonBlockEnd(lines, defIdx)
+}
+
+// PythonBlockEnd Returns the index of the last line of the body of the
+// declaration on lines[defIdx]
+func PythonBlockEnd(lines []string, defIdx int) int {
+	defIndent := indentation(lines[defIdx])
+	inString := pythonStringLines(lines)
+
+	// Signatures can span multiple lines (i.e. closing paren on its own line)
+	bodyStart := defIdx
+	for bodyStart < len(lines)-1 {
+		code, _, _ := strings.Cut(lines[bodyStart], "#")
+		if strings.HasSuffix(strings.TrimSpace(code), ":") {
+			break
+		}
+		bodyStart++
+	}
+
+	end := bodyStart
+	for i := bodyStart + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || inString[i] {
+			continue
+		}
+		if indentation(lines[i]) <= defIndent {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+func (Python) FixtureAt(lines []string, defIdx int) (Scope, bool) {
+	return PythonFixture(lines, defIdx)
+}
+
+// PythonFixture Checks the decorators above the method declared on lines[defIdx]
+// and returns the fixture scope if the method is a pytest fixture.
+func PythonFixture(lines []string, defIdx int) (Scope, bool) {
+	match := pythonFixturePattern.FindStringSubmatch(pythonDecorators(lines, defIdx))
+	if match == nil {
+		return Scope{}, false
+	}
+
+	name := MatchContainerName(MethodContainer, lines[defIdx])
+	args := match[pythonFixturePattern.SubexpIndex("args")]
+	if nameMatch := pythonFixtureNamePattern.FindStringSubmatch(args); nameMatch != nil {
+		name = nameMatch[1]
+	}
+
+	return Scope{
+		Name:    strings.TrimSpace(name),
+		Kind:    FixtureScope,
+		Autouse: pythonAutousePattern.MatchString(args),
+	}, true
+}
+
+// PythonKeywordName Returns the name that Robot Framework uses for the method
+// declared on lines[defIdx] if it is set by the @keyword decorator ("" if not)
+func PythonKeywordName(lines []string, defIdx int) string {
+	match := pythonKeywordPattern.FindStringSubmatch(pythonDecorators(lines, defIdx))
+	if match == nil {
+		return ""
+	}
+	return match[pythonKeywordPattern.SubexpIndex("name")]
+}
+
+// pythonDecorators Returns everything between the declaration on
+// lines[defIdx] and the end of the previous block. This includes decorators
+// spanning multiple lines.
+func pythonDecorators(lines []string, defIdx int) string {
+	decorators := []string{}
+	for i := defIdx - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasSuffix(line, ":") {
+			break
+		}
+		decorators = append([]string{line}, decorators...)
+	}
+	return strings.Join(decorators, " ")
+}
+
+// IsGenerated Protobuf generated modules (and their stubs) are skipped
+func (Python) IsGenerated(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(path, filepath.Ext(path)), "_pb2")
+}
+
+func (Python) IsTcFile(path string) bool {
+	sep := string(filepath.Separator)
+	if sep != "/" {
+		sep = `\\` // on WIN make sure to escape the backslash
+	}
+	testCasePathPattern := fmt.Sprintf(`test_cases%s.*?%stest_[^%s]*\.py$`, sep, sep, sep)
+
+	tcPathPattern, err := regexp.Compile(testCasePathPattern)
+	if err != nil {
+		errorTxt := fmt.Sprintf("Couldn't compile TC path pattern: %v", err)
+		log.Fatal(ErrorStyle.Render(errorTxt))
+	}
+	isTc := tcPathPattern.FindString(path) != ""
+	return isTc
+}
+
+// TestCaseTexts There is one TC per python TC file
+func (Python) TestCaseTexts(text string, _ int) []string {
+	return []string{text}
+}
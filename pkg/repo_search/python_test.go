@@ -0,0 +1,46 @@
+package repo_search
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPythonIsGenerated(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"lib/proto/heater_pb2.py", true},
+		{"lib/proto/heater_pb2.pyi", true},
+		{"lib/proto/heater_pb2.pyx", true},
+		{"lib/proto/heater.py", false},
+		{"lib/proto/heater_pb2_helpers.py", false},
+		{"lib/proto/pb2.py", false},
+	}
+	for _, tt := range tests {
+		if got := (Python{}).IsGenerated(filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("IsGenerated(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPythonIsTcFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"test_automation/test_cases/heater/test_001_power.py", true},
+		{"test_automation/test_cases/heater/sub/test_002_off.py", true},
+		{"test_automation/test_cases/heater/test_001_power.pyi", false},
+		{"test_automation/test_cases/heater/test_001_power.pyx", false},
+		{"test_automation/test_cases/heater/test_001_power.py.orig", false},
+		{"test_automation/test_cases/heater/conftest.py", false},
+		{"test_automation/test_cases/test_data/helpers.py", false},
+		{"test_automation/lib/heater/test_001_power.py", false},
+	}
+	for _, tt := range tests {
+		if got := (Python{}).IsTcFile(filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("IsTcFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package repo_search
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Declaration Function, method, keyword or fixture declared in a (non TC) file
+type Declaration struct {
+	File  string
+	Scope Scope
+	// Line Line of the declaration (1-based)
+	Line int
+	// end Index of the last line of the body
+	end    int
+	symbol Symbol
+}
+
+// Name Returns the name of the declaration as it is written in code (i.e. Heater.power)
+func (d Declaration) Name() string {
+	switch {
+	case d.Scope.Kind == ConstructorScope:
+		return d.Scope.Class + "." + constructorName(d.File)
+	case d.Scope.Class != "":
+		return d.Scope.Class + "." + d.Scope.Name
+	}
+	return d.Scope.Name
+}
+
+// ReachedDeclaration Declaration that is (transitively) called by a TC
+type ReachedDeclaration struct {
+	Declaration
+	RelPath string
+	// Depth Number of calls from the TC to the declaration (1 for direct calls)
+	Depth int
+	// Chain Names of the declarations from the first one called by the TC to
+	// this one
+	Chain []string
+}
+
+// ReachIndex Declarations of the repo and the lines they are used on. It goes
+// the opposite way of SearchForUsagesInTc: from TCs to the code they reach.
+type ReachIndex struct {
+	repo         *Repo
+	declarations []Declaration
+	// usages File -> line index -> indexes of the declarations used on that line
+	usages map[string]map[int][]int
+	// fixtureScopes Declaration index of a fixture -> where it can be requested
+	fixtureScopes map[int][]string
+	// classReferences Declaration index of a method -> files that refer to its class
+	classReferences map[int]map[string]bool
+}
+
+// TcLocation TC file and the parts of it that belong to the TC
+type TcLocation struct {
+	File string
+	// Lines First and last line index of each part of File that belongs to
+	// the TC (nil if the whole file does)
+	Lines [][]int
+}
+
+func NewReachIndex(repo *Repo) *ReachIndex {
+	return &ReachIndex{
+		repo:            repo,
+		declarations:    FindDeclarations(repo, repo.files),
+		usages:          map[string]map[int][]int{},
+		fixtureScopes:   map[int][]string{},
+		classReferences: map[int]map[string]bool{},
+	}
+}
+
+// FindDeclarations Returns the searchable functions, methods, keywords and
+// fixtures declared in the (non TC) files of files. Only languages that know
+// where a declaration ends (see BlockLanguage) have declarations.
+func FindDeclarations(repo *Repo, files []string) []Declaration {
+	declarations := []Declaration{}
+	for _, file := range files {
+		lang, ok := LanguageForFile(file).(BlockLanguage)
+		if !ok || lang.IsTcFile(file) {
+			continue
+		}
+
+		text, err := repo.ReadText(file)
+		if err != nil {
+			errorTxt := fmt.Sprintf("ERROR: Couldn't read file %s: %v", file, err)
+			log.Print(ErrorStyle.Render(errorTxt))
+			continue
+		}
+
+		lines := strings.Split(text, "\n")
+		lineEnd := 0
+		for i, line := range lines {
+			lineEnd += len(line)
+			pretext := text[:lineEnd]
+			lineEnd++
+
+			name := DeclarationNameAt(lang, lines, i)
+			if name == "" {
+				continue
+			}
+			end := lang.BlockEnd(lines, i)
+			scope := lang.EnclosingScope(pretext, line)
+
+			// The body can start on a later line (i.e. a C function with the
+			// opening brace on its own line)
+			headerEnd := len(pretext)
+			for j := i + 1; scope.Name == "" && j <= end; j++ {
+				headerEnd += len(lines[j]) + 1
+				if headerScope := lang.EnclosingScope(text[:headerEnd], lines[j]); headerScope.Name == name {
+					scope = headerScope
+				}
+			}
+			if scope.Name == "" {
+				continue
+			}
+
+			declaration := Declaration{
+				File:  file,
+				Scope: scope,
+				Line:  i + 1,
+				end:   end,
+			}
+			if scope.Kind != FixtureScope {
+				declaration.symbol = repo.Symbol(scope, file)
+			}
+			declarations = append(declarations, declaration)
+		}
+	}
+	return declarations
+}
+
+// Reached Returns the declarations called by the TC (and the ones called by
+// them) up to depth calls away, sorted by depth
+func (r *ReachIndex) Reached(tc TcLocation, depth int) []ReachedDeclaration {
+	type region struct {
+		file       string
+		start, end int
+		depth      int
+		chain      []string
+	}
+
+	reached := []ReachedDeclaration{}
+	seen := map[int]bool{}
+	reach := func(idx int, from region) region {
+		seen[idx] = true
+		declaration := r.declarations[idx]
+		chain := append(append([]string{}, from.chain...), declaration.Name())
+		reached = append(reached, ReachedDeclaration{
+			Declaration: declaration,
+			RelPath:     r.repo.RelPath(declaration.File),
+			Depth:       from.depth + 1,
+			Chain:       chain,
+		})
+		return region{declaration.File, declaration.Line - 1, declaration.end, from.depth + 1, chain}
+	}
+
+	tcRegion := region{file: tc.File, start: 0, end: -1}
+	queue := []region{}
+	if tc.Lines == nil {
+		queue = append(queue, tcRegion)
+	}
+	for _, lines := range tc.Lines {
+		queue = append(queue, region{file: tc.File, start: lines[0], end: lines[1]})
+	}
+
+	// Autouse fixtures run for every TC in their scope without being requested
+	for _, idx := range r.visibleFixtures(tc.File) {
+		if r.declarations[idx].Scope.Autouse && !seen[idx] {
+			queue = append(queue, reach(idx, tcRegion))
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.depth >= depth {
+			continue
+		}
+
+		usages := r.usagesIn(current.file)
+		lineIdxs := make([]int, 0, len(usages))
+		for lineIdx := range usages {
+			inRegion := lineIdx >= current.start && (current.end == -1 || lineIdx <= current.end)
+			if inRegion {
+				lineIdxs = append(lineIdxs, lineIdx)
+			}
+		}
+		// Breadth first in order of appearance -> stable chains
+		sort.Ints(lineIdxs)
+
+		for _, lineIdx := range lineIdxs {
+			for _, idx := range usages[lineIdx] {
+				if !seen[idx] {
+					queue = append(queue, reach(idx, current))
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(reached, func(i, j int) bool {
+		if reached[i].Depth != reached[j].Depth {
+			return reached[i].Depth < reached[j].Depth
+		}
+		if reached[i].RelPath != reached[j].RelPath {
+			return reached[i].RelPath < reached[j].RelPath
+		}
+		return reached[i].Line < reached[j].Line
+	})
+	return reached
+}
+
+// usagesIn Returns the declarations used on each line of file
+func (r *ReachIndex) usagesIn(file string) map[int][]int {
+	if usages, ok := r.usages[file]; ok {
+		return usages
+	}
+
+	usages := map[int][]int{}
+	r.usages[file] = usages
+
+	text, err := r.repo.ReadText(file)
+	if err != nil {
+		errorTxt := fmt.Sprintf("ERROR: Couldn't read file %s: %v", file, err)
+		log.Print(ErrorStyle.Render(errorTxt))
+		return usages
+	}
+	lang := LanguageForFile(file)
+	lines := strings.Split(text, "\n")
+
+	lineStarts := []int{0}
+	for i, c := range text {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineOf := func(pos int) int {
+		return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > pos }) - 1
+	}
+	addUsage := func(lineIdx, idx int) {
+		if !containsInt(usages[lineIdx], idx) {
+			usages[lineIdx] = append(usages[lineIdx], idx)
+		}
+	}
+
+	fixtureLang, hasFixtures := lang.(FixtureLanguage)
+	moduleLang, hasModules := lang.(ModuleLanguage)
+	fixtures := r.visibleFixtures(file)
+	// Line index -> used name -> declarations with that name
+	methodUsages := map[int]map[string][]int{}
+	for idx, declaration := range r.declarations {
+		if declaration.Scope.Kind == FixtureScope {
+			if !hasFixtures || fixtures[declaration.Scope.Name] != idx {
+				continue
+			}
+			for _, request := range fixtureLang.FixtureRequests(text, declaration.Scope.Name) {
+				addUsage(lineOf(request.match[0]), idx)
+			}
+			continue
+		}
+
+		for _, match := range declaration.symbol.FindAll(text, lang, file) {
+			lineIdx := lineOf(match[0])
+			line := lines[lineIdx]
+
+			// Declarations (i.e. overrides) and imports don't call anything
+			col := match[0] - lineStarts[lineIdx]
+			isDeclaration := IsDeclarationMatch(lang, lines, lineIdx, col, col+match[1]-match[0]) ||
+				lang.DeclarationName(ClassContainer, line) != ""
+			isImport := hasModules && moduleLang.IsImport(line)
+			if isDeclaration || isImport {
+				continue
+			}
+			if declaration.Scope.Class != "" && declaration.Scope.Kind != ConstructorScope {
+				if methodUsages[lineIdx] == nil {
+					methodUsages[lineIdx] = map[string][]int{}
+				}
+				name := text[match[0]:match[1]]
+				methodUsages[lineIdx][name] = append(methodUsages[lineIdx][name], idx)
+				continue
+			}
+			addUsage(lineIdx, idx)
+		}
+	}
+
+	for lineIdx, byName := range methodUsages {
+		for name, idxs := range byName {
+			resolved := r.resolveMethods(file, idxs)
+			for _, idx := range resolved {
+				addUsage(lineIdx, idx)
+			}
+			if len(resolved) == 0 {
+				warningTxt := fmt.Sprintf(
+					"Couldn't tell which of %d declarations of %s is used in %s:%d. Not following it.",
+					len(idxs), name, r.repo.RelPath(file), lineIdx+1,
+				)
+				log.Println(WarningStyle.Render(warningTxt))
+			}
+		}
+	}
+	return usages
+}
+
+// resolveMethods Returns the methods (declaration indexes) with the same name
+// that a usage in file can call. Like the forward search, a name declared by
+// several classes is only followed if file refers to the class (or a subclass).
+func (r *ReachIndex) resolveMethods(file string, idxs []int) []int {
+	if len(idxs) == 1 {
+		return idxs
+	}
+
+	resolved := []int{}
+	for _, idx := range idxs {
+		references, ok := r.classReferences[idx]
+		if !ok {
+			declaration := r.declarations[idx]
+			references = r.repo.ClassReferences(declaration.File, declaration.Scope.Class)
+			r.classReferences[idx] = references
+		}
+		if references[file] {
+			resolved = append(resolved, idx)
+		}
+	}
+	return resolved
+}
+
+// visibleFixtures Returns the fixtures that can be requested in file (fixture
+// name -> declaration index). Fixtures of closer scopes (the file itself,
+// then the closest conftest) win.
+func (r *ReachIndex) visibleFixtures(file string) map[string]int {
+	fixtures := map[string]int{}
+	scopeLens := map[string]int{}
+	for idx, declaration := range r.declarations {
+		if declaration.Scope.Kind != FixtureScope {
+			continue
+		}
+
+		scopes, ok := r.fixtureScopes[idx]
+		if !ok {
+			scopes = r.repo.FixtureScopes(declaration.File, declaration.Scope)
+			r.fixtureScopes[idx] = scopes
+		}
+		scopeLen, ok := InFixtureScopes(file, scopes)
+		if !ok {
+			continue
+		}
+
+		name := declaration.Scope.Name
+		if current, ok := scopeLens[name]; ok && current >= scopeLen {
+			continue
+		}
+		fixtures[name] = idx
+		scopeLens[name] = scopeLen
+	}
+	return fixtures
+}
+
+// FindTc Returns where the TC of a TC ID or of a path (relative to its root)
+// is. A TC of a suite (see SuiteLanguage) is only its part of the file.
+func FindTc(repo *Repo, idOrPath string) (TcLocation, bool) {
+	if file, ok := repo.FindFile(idOrPath); ok {
+		return TcLocation{File: file}, true
+	}
+
+	for _, file := range repo.files {
+		lang := LanguageForFile(file)
+		if !lang.IsTcFile(file) {
+			continue
+		}
+
+		text, err := repo.ReadText(file)
+		if err != nil {
+			continue
+		}
+
+		suiteLang, ok := lang.(SuiteLanguage)
+		if !ok {
+			for _, info := range TestCasesAt(lang, text, file, 0, map[string]TestCaseInfo{}) {
+				if info.id == idOrPath {
+					return TcLocation{File: file}, true
+				}
+			}
+			continue
+		}
+
+		testCases, shared := suiteLang.TestCaseRanges(text)
+		for _, tcRange := range testCases {
+			if ProcessTc(text[tcRange[0]:tcRange[1]], file).id != idOrPath {
+				continue
+			}
+
+			location := TcLocation{File: file}
+			for _, part := range append([][]int{tcRange}, shared...) {
+				first := strings.Count(text[:part[0]], "\n")
+				last := first + strings.Count(strings.TrimSuffix(text[part[0]:part[1]], "\n"), "\n")
+				location.Lines = append(location.Lines, []int{first, last})
+			}
+			return location, true
+		}
+	}
+	return TcLocation{}, false
+}
+
+// SharedDeclarations Returns the declarations reached through both a and b
+func SharedDeclarations(a, b []ReachedDeclaration) []Declaration {
+	inA := map[string]bool{}
+	for _, declaration := range a {
+		inA[declaration.key()] = true
+	}
+
+	shared := []Declaration{}
+	for _, declaration := range b {
+		if inA[declaration.key()] {
+			shared = append(shared, declaration.Declaration)
+		}
+	}
+	return shared
+}
+
+func (d Declaration) key() string {
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+var ReachedCsvHeader = []string{"TC", "Depth", "File", "Line", "Function", "Chain"}
+
+// ReachedCsvRecords One row per declaration reached by each TC (tc -> reached)
+func ReachedCsvRecords(tcs []string, reached map[string][]ReachedDeclaration) [][]string {
+	records := [][]string{ReachedCsvHeader}
+	for _, tc := range tcs {
+		for _, declaration := range reached[tc] {
+			records = append(records, []string{
+				tc,
+				fmt.Sprint(declaration.Depth),
+				declaration.RelPath,
+				fmt.Sprint(declaration.Line),
+				declaration.Name(),
+				formatChain(declaration.Chain),
+			})
+		}
+	}
+	return records
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
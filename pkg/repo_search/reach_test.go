@@ -0,0 +1,186 @@
+package repo_search
+
+import (
+	"strings"
+	"testing"
+)
+
+// reachedNames Returns the names of the declarations reached by tc
+func reachedNames(t *testing.T, repo *Repo, tc string, depth int) []string {
+	t.Helper()
+	location, ok := FindTc(repo, tc)
+	if !ok {
+		t.Fatalf("couldn't find TC %s", tc)
+	}
+
+	names := []string{}
+	for _, declaration := range NewReachIndex(repo).Reached(location, depth) {
+		names = append(names, declaration.Name())
+	}
+	return names
+}
+
+func TestReachedMethodsOfImportedClasses(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.py": "class Heater:\n    def connect(self):\n        self.reset()\n\n" +
+			"    def reset(self):\n        pass\n",
+		"lib/pump.py": "class Pump:\n    def connect(self):\n        pass\n\n    def reset(self):\n        pass\n",
+		"lib/bench.py": "from lib.heater import Heater\nfrom lib.pump import Pump\n\n" +
+			"def connect_all(devices):\n    for device in devices:\n        device.connect()\n",
+		"test_cases/heater/test_001_connect.py": `"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+from lib.heater import Heater
+
+def test_001_connect():
+    Heater().connect()
+`,
+		"test_cases/heater/test_002_bench.py": `"""
+Polarion ID: 4AP2-1002
+Setup: Bench A
+"""
+from lib.bench import connect_all
+
+def test_002_bench(devices):
+    connect_all(devices)
+`,
+		"test_cases/heater/test_003_unrelated.py": `"""
+Polarion ID: 4AP2-1003
+Setup: Bench A
+"""
+def test_003_unrelated(device):
+    device.connect()
+`,
+	})
+
+	tests := []struct {
+		tc   string
+		want []string
+	}{
+		// Pump.connect has the same name but Pump isn't imported
+		{"4AP2-1001", []string{"Heater.connect", "Heater.reset"}},
+		// Both classes are imported -> the call can reach either of them
+		{"4AP2-1002", []string{"connect_all", "Heater.connect", "Pump.connect", "Heater.reset"}},
+		// Neither is imported -> can't tell which one is called
+		{"4AP2-1003", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tc, func(t *testing.T) {
+			if got := reachedNames(t, repo, tt.tc, 5); !equalStrings(got, tt.want) {
+				t.Errorf("reached = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReachedRobotTestCase(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.resource": `*** Keywords ***
+Set Power
+    [Arguments]    ${value}
+    Apply Power    ${value}
+
+Apply Power
+    [Arguments]    ${value}
+    Log    ${value}
+
+Switch Off
+    Log    off
+
+Prepare Bench
+    Log    prepare
+`,
+		"test_cases/heater/heater.robot": `*** Settings ***
+Resource    ../../lib/heater.resource
+Test Setup    Prepare Bench
+
+*** Test Cases ***
+Heater Power
+    [Documentation]    Polarion ID: 4AP2-1001
+    ...    Setup: Bench A
+    Set Power    10
+
+Heater Off
+    [Documentation]    Polarion ID: 4AP2-1002
+    ...    Setup: Bench A
+    Switch Off
+`,
+	})
+
+	tests := []struct {
+		tc   string
+		want []string
+	}{
+		// Test Setup of the suite settings runs for every TC
+		{"4AP2-1001", []string{"Set Power", "Prepare Bench", "Apply Power"}},
+		{"4AP2-1002", []string{"Switch Off", "Prepare Bench"}},
+		{"test_cases/heater/heater.robot", []string{"Set Power", "Switch Off", "Prepare Bench", "Apply Power"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tc, func(t *testing.T) {
+			if got := reachedNames(t, repo, tt.tc, 5); !equalStrings(got, tt.want) {
+				t.Errorf("reached = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReachedCFunctions(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"sim/sim.c": `#include "sim.h"
+
+static int apply(int v)
+{
+    return v;
+}
+
+int sim_power(int v) {
+    if (v > 0) {
+        return apply(v);
+    }
+    return 0;
+}
+
+int unused(void);
+`,
+		"test_cases/sim/test_001_sim.py": `"""
+Polarion ID: 4AP2-1001
+Setup: Bench A
+"""
+def test_001_sim(dll):
+    dll.sim_power(10)
+`,
+	})
+
+	want := []string{"sim_power", "apply"}
+	if got := reachedNames(t, repo, "4AP2-1001", 5); !equalStrings(got, want) {
+		t.Errorf("reached = %q, want %q", got, want)
+	}
+}
+
+func TestBlockEnd(t *testing.T) {
+	tests := []struct {
+		name   string
+		lang   BlockLanguage
+		text   string
+		defIdx int
+		want   int
+	}{
+		{"c function", Cpp{}, "int f(void)\n{\n    if (x) {\n    }\n}\nint g;\n", 0, 4},
+		{"c one line", Cpp{}, "int f(void) { return 1; }\nint g;\n", 0, 0},
+		{"c braces in comments", Cpp{}, "int f(void) {\n    // }\n    /* } */\n}\n", 0, 3},
+		{"c prototype", Cpp{}, "int f(void);\nint g(void) {\n}\n", 0, 0},
+		{"robot keyword", Robot{}, "Set Power\n    Log    1\n\n    # comment\n    Log    2\n\nOther\n", 0, 4},
+		{"robot last keyword", Robot{}, "Set Power\n    Log    1\n", 0, 1},
+		{"python function", Python{}, "def f():\n    x = 1\n\n    return x\n\ny = 2\n", 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.text, "\n")
+			if got := tt.lang.BlockEnd(lines, tt.defIdx); got != tt.want {
+				t.Errorf("BlockEnd = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,491 @@
+package repo_search
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repo Files of the searched directories (roots) and the indexes built from
+// them. It is built once and shared by all (recursive) searches.
+type Repo struct {
+	// Roots Searched directories. Python modules are named relative to their root
+	// (as if every root was on the python path).
+	Roots  []Root
+	Filter FileFilter
+	// Context Lines shown around each match
+	Context MatchContext
+	// Output Where results of searches are printed (stdout by default)
+	Output io.Writer
+	// Recorder Also receives the printed results if not nil (i.e. to export them)
+	Recorder *ResultRecorder
+	// Trace Records what the searches depend on if not nil (see SearchTrace)
+	Trace *SearchTrace
+
+	files   []string
+	listed  []string
+	imports *ImportIndex
+	classes *ClassIndex
+	// plugins Files loaded as plugins (their fixtures are available everywhere)
+	plugins map[string]bool
+
+	// Files are only read and searched again after Refresh found them changed.
+	// Only the results of the last resultCacheSize searches are kept.
+	mu          sync.Mutex
+	stats       map[string]fileStat
+	texts       map[string]string
+	results     map[string]*list.Element // search key -> element of resultOrder
+	resultOrder *list.List               // *searchResults, most recently used first
+}
+
+// resultCacheSize Number of searches (patterns) whose results are cached
+const resultCacheSize = 256
+
+// searchResults Cached results of one search, per file
+type searchResults struct {
+	key   string
+	files map[string]cachedResult
+}
+
+// cachedResult Result of searching a file. For symbols it is only valid as
+// long as the names the symbol is known by in the file stay the same.
+type cachedResult struct {
+	names  string
+	result *FileResult
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewRepo Walks the roots for files to search. If listed is not nil only the
+// listed files are searched instead.
+func NewRepo(roots []Root, filter FileFilter, listed []string) *Repo {
+	repo := &Repo{
+		Roots:       roots,
+		Filter:      filter,
+		Output:      os.Stdout,
+		listed:      listed,
+		texts:       map[string]string{},
+		results:     map[string]*list.Element{},
+		resultOrder: list.New(),
+	}
+
+	repo.files = repo.walk()
+	repo.stats = repo.statFiles()
+	repo.buildIndexes()
+	return repo
+}
+
+// record Passes the results found by searching for searchTerm to the recorder (if any)
+func (r *Repo) record(searchTerm string, results ...SearchResult) {
+	if r.Recorder == nil {
+		return
+	}
+	for _, result := range results {
+		r.Recorder.Record(searchTerm, result)
+	}
+}
+
+// Close Closes the roots (open archives and git processes)
+func (r *Repo) Close() {
+	for _, root := range r.Roots {
+		if err := root.Close(); err != nil {
+			errorTxt := fmt.Sprintf("ERROR: Couldn't close %s: %v", root.Dir, err)
+			log.Print(ErrorStyle.Render(errorTxt))
+		}
+	}
+}
+
+// walk Returns the files of all roots (or the listed files)
+func (r *Repo) walk() []string {
+	var rootFiles [][]string
+	if r.listed != nil {
+		rootFiles = append(rootFiles, GetListedFiles(r.Roots, r.listed, r.Filter))
+	} else {
+		for _, root := range r.Roots {
+			files, err := GetFilesFromRoot(root, r.Filter)
+			if err != nil {
+				errorTxt := fmt.Sprintf("Couldn't get list of files for dir %s: %v", root.Dir, err)
+				log.Fatalf(errorTxt)
+			}
+			rootFiles = append(rootFiles, files)
+		}
+	}
+
+	// Roots can be nested in each other
+	files := []string{}
+	seen := map[string]bool{}
+	for _, rootFiles := range rootFiles {
+		for _, file := range rootFiles {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+func (r *Repo) statFiles() map[string]fileStat {
+	stats := map[string]fileStat{}
+	for _, file := range r.files {
+		idx := rootIndex(r.RootDirs(), file)
+		if idx == -1 {
+			continue
+		}
+		rel, ok := r.Roots[idx].RootFile(file)
+		if !ok {
+			continue
+		}
+		// Files that can't be stat-ed are treated as changed on every refresh
+		info, err := fs.Stat(r.Roots[idx].FS, rel)
+		if err != nil {
+			continue
+		}
+		stats[file] = fileStat{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stats
+}
+
+func (r *Repo) buildIndexes() {
+	texts := r.moduleTexts()
+	r.imports = BuildImportIndex(r.RootDirs(), texts)
+	r.classes = BuildClassIndex(texts, r.imports)
+
+	r.plugins = map[string]bool{}
+	for file, text := range texts {
+		lang, ok := LanguageForFile(file).(FixtureLanguage)
+		if !ok {
+			continue
+		}
+		for _, plugin := range lang.Plugins(text) {
+			if pluginFile := r.imports.File(r.imports.ResolveModule(plugin)); pluginFile != "" {
+				r.plugins[pluginFile] = true
+			}
+		}
+	}
+}
+
+// Refresh Looks for files that were added, changed or removed since the repo
+// was built (or last refreshed). Their texts and search results are dropped
+// and the import and class indexes are rebuilt. Returns the changed files.
+func (r *Repo) Refresh() []string {
+	oldStats := r.stats
+	r.files = r.walk()
+	r.stats = r.statFiles()
+
+	changed := []string{}
+	for _, file := range r.files {
+		oldStat, ok := oldStats[file]
+		newStat, statOk := r.stats[file]
+		if !ok || !statOk || !oldStat.modTime.Equal(newStat.modTime) || oldStat.size != newStat.size {
+			changed = append(changed, file)
+		}
+	}
+	for file := range oldStats {
+		if _, ok := r.stats[file]; !ok && !containsString(changed, file) {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	if len(changed) == 0 {
+		return changed
+	}
+
+	r.mu.Lock()
+	for _, file := range changed {
+		delete(r.texts, file)
+		for elem := r.resultOrder.Front(); elem != nil; elem = elem.Next() {
+			delete(elem.Value.(*searchResults).files, file)
+		}
+	}
+	r.mu.Unlock()
+
+	r.buildIndexes()
+	return changed
+}
+
+// cachedSearch Searches file for pattern unless the same search of the file
+// was already done (and is still cached)
+func (r *Repo) cachedSearch(file string, pattern Matcher) *FileResult {
+	key := searchKey(pattern)
+	names := searchNames(pattern, file)
+
+	r.mu.Lock()
+	if elem, ok := r.results[key]; ok {
+		r.resultOrder.MoveToFront(elem)
+		cached, ok := elem.Value.(*searchResults).files[file]
+		if ok && cached.names == names {
+			r.mu.Unlock()
+			return cached.result
+		}
+	}
+	r.mu.Unlock()
+
+	result := SearchFile(r, file, pattern)
+
+	r.mu.Lock()
+	elem, ok := r.results[key]
+	if !ok {
+		elem = r.resultOrder.PushFront(&searchResults{key: key, files: map[string]cachedResult{}})
+		r.results[key] = elem
+
+		if r.resultOrder.Len() > resultCacheSize {
+			oldest := r.resultOrder.Back()
+			r.resultOrder.Remove(oldest)
+			delete(r.results, oldest.Value.(*searchResults).key)
+		}
+	}
+	elem.Value.(*searchResults).files[file] = cachedResult{names: names, result: result}
+	r.mu.Unlock()
+	return result
+}
+
+// searchKey Identifies what pattern looks for
+func searchKey(pattern Matcher) string {
+	if symbol, ok := pattern.(Symbol); ok {
+		return "symbol " + symbol.Key()
+	}
+	return fmt.Sprintf("%T %s", pattern, pattern.String())
+}
+
+// searchNames Symbols are found by their names in the file, which change with
+// the imports of the file
+func searchNames(pattern Matcher, file string) string {
+	if symbol, ok := pattern.(Symbol); ok {
+		return fmt.Sprint(symbol.NamesIn(file))
+	}
+	return ""
+}
+
+// FilesIn Returns the repo files inside of dir (and its subdirectories).
+// An empty dir stands for all roots.
+func (r *Repo) FilesIn(dir string) []string {
+	if dir == "" {
+		return r.files
+	}
+
+	dir = filepath.Clean(dir)
+
+	files := []string{}
+	for _, file := range r.files {
+		if strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// FindFile Returns the repo file at path. The path is either relative to the
+// root of the file or the file path itself.
+func (r *Repo) FindFile(path string) (string, bool) {
+	for _, file := range r.files {
+		if file == filepath.Clean(path) || r.RelPath(file) == filepath.ToSlash(path) {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+func (r *Repo) RootDirs() []string {
+	dirs := []string{}
+	for _, root := range r.Roots {
+		dirs = append(dirs, root.Dir)
+	}
+	return dirs
+}
+
+// RelPath Returns the slash separated path of file relative to its root
+func (r *Repo) RelPath(file string) string {
+	idx := rootIndex(r.RootDirs(), file)
+	if idx == -1 {
+		return filepath.ToSlash(file)
+	}
+
+	rel, ok := r.Roots[idx].RootFile(file)
+	if !ok {
+		return filepath.ToSlash(file)
+	}
+	return rel
+}
+
+// ReadText Reads a repo file as text with \n line endings (CRLF is converted)
+func (r *Repo) ReadText(file string) (string, error) {
+	r.mu.Lock()
+	text, ok := r.texts[file]
+	r.mu.Unlock()
+	if ok {
+		return text, nil
+	}
+
+	data, err := r.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	text = strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	r.mu.Lock()
+	r.texts[file] = text
+	r.mu.Unlock()
+	return text, nil
+}
+
+// ReadFile Reads a repo file from the file system of its root
+func (r *Repo) ReadFile(file string) ([]byte, error) {
+	idx := rootIndex(r.RootDirs(), file)
+	if idx == -1 {
+		return nil, fmt.Errorf("%s is not in any of the searched dirs", file)
+	}
+
+	rel, ok := r.Roots[idx].RootFile(file)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in %s", file, r.Roots[idx].Dir)
+	}
+	return fs.ReadFile(r.Roots[idx].FS, rel)
+}
+
+// RootOf Returns the (innermost) root that contains file or "" if there is none
+func RootOf(roots []string, file string) string {
+	if idx := rootIndex(roots, file); idx != -1 {
+		return roots[idx]
+	}
+	return ""
+}
+
+func rootIndex(roots []string, file string) int {
+	file = filepath.Clean(file)
+	found, foundLen := -1, 0
+	for i, root := range roots {
+		root = filepath.Clean(root)
+		// Paths walked from "." have no prefix
+		inRoot := root == "." || file == root || strings.HasPrefix(file, root+string(filepath.Separator))
+		if inRoot && (found == -1 || len(root) > foundLen) {
+			found, foundLen = i, len(root)
+		}
+	}
+	return found
+}
+
+// Symbol Creates a symbol for the method of scope declared in file definedIn.
+// Functions are searched where they are imported, methods of classes in every
+// file cause they are called on instances that can come from anywhere (i.e.
+// fixtures or attributes of other objects).
+func (r *Repo) Symbol(scope Scope, definedIn string) Symbol {
+	symbol := Symbol{
+		Name:      scope.Name,
+		Class:     scope.Class,
+		DefinedIn: definedIn,
+		Kind:      scope.Kind,
+		Keyword:   scope.Keyword,
+		names:     r.imports.SymbolNames(scope.Name, definedIn, nil),
+	}
+	if symbol.names == nil {
+		return symbol
+	}
+
+	// Methods are called through instances of their class -> the class is imported
+	imported := scope.Name
+	if scope.Class != "" {
+		imported = scope.Class
+	}
+	if unresolved := r.imports.UnresolvedImporters(definedIn, imported); len(unresolved) > 0 {
+		relPaths := []string{}
+		for _, file := range unresolved {
+			relPaths = append(relPaths, r.RelPath(file))
+		}
+		warningTxt := fmt.Sprintf(
+			"Couldn't resolve the import of %s in %s. Searching for %s in all files.",
+			imported,
+			strings.Join(relPaths, ", "),
+			scope.Name,
+		)
+		log.Println(WarningStyle.Render(warningTxt))
+		symbol.names = nil
+		return symbol
+	}
+
+	if scope.Class == "" {
+		return symbol
+	}
+
+	if scope.Kind == ConstructorScope {
+		// Instantiating a subclass runs the constructor if the subclass
+		// inherits it or calls it through super()
+		reaching, _ := r.classes.SubclassesReaching(definedIn, scope.Class, constructorName(definedIn), r.imports)
+		for file, classes := range reaching {
+			for _, class := range classes {
+				mergeNames(symbol.names, r.imports.SymbolNames(class, file, nil))
+			}
+		}
+		return symbol
+	}
+
+	reaching, hierarchy := r.classes.SubclassesReaching(definedIn, scope.Class, scope.Name, r.imports)
+	symbol.hierarchy = hierarchy
+	for file, classes := range reaching {
+		// Only modules that import the subclasses (and not some other
+		// class from the same module) can call the method through them
+		mergeNames(symbol.names, r.imports.SymbolNames(scope.Name, file, classes))
+	}
+	return symbol
+}
+
+// ClassReferences Returns the files that refer to class className (declared in
+// definedIn) or to one of its subclasses by name
+func (r *Repo) ClassReferences(definedIn, className string) map[string]bool {
+	files := map[string]bool{}
+	addFiles := func(names map[string][]string) {
+		for file := range names {
+			files[file] = true
+		}
+	}
+
+	addFiles(r.imports.SymbolNames(className, definedIn, []string{className}))
+	// No method is overridden by name "" -> all subclasses
+	subclasses, _ := r.classes.SubclassesReaching(definedIn, className, "", r.imports)
+	for file, classes := range subclasses {
+		for _, class := range classes {
+			addFiles(r.imports.SymbolNames(class, file, []string{class}))
+		}
+	}
+	return files
+}
+
+func mergeNames(names, other map[string][]string) {
+	for file, otherNames := range other {
+		for _, name := range otherNames {
+			if !containsString(names[file], name) {
+				names[file] = append(names[file], name)
+			}
+		}
+	}
+}
+
+// moduleTexts Returns the texts of the files whose language has modules
+func (r *Repo) moduleTexts() map[string]string {
+	texts := map[string]string{}
+	for _, file := range r.files {
+		if _, ok := LanguageForFile(file).(ModuleLanguage); !ok {
+			continue
+		}
+
+		text, err := r.ReadText(file)
+		if err != nil {
+			errorTxt := fmt.Sprintf("ERROR: Couldn't read file %s: %v", file, err)
+			log.Print(ErrorStyle.Render(errorTxt))
+			continue
+		}
+		texts[file] = text
+	}
+	return texts
+}
@@ -0,0 +1,36 @@
+package repo_search
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCachedSearchIsBounded(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/heater.py": {Data: []byte("def power(v):\n    apply(v)\n")},
+	}
+	repo := NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py"}}, nil)
+	file := "repo/lib/heater.py"
+
+	first := repo.cachedSearch(file, Literal("apply"))
+	if first == nil {
+		t.Fatal("expected a result for apply")
+	}
+	if again := repo.cachedSearch(file, Literal("apply")); again != first {
+		t.Error("expected the cached result for a repeated search")
+	}
+
+	for i := 0; i < 2*resultCacheSize; i++ {
+		repo.cachedSearch(file, Literal(fmt.Sprintf("missing_%d", i)))
+	}
+	if len(repo.results) != resultCacheSize || repo.resultOrder.Len() != resultCacheSize {
+		t.Errorf("cache holds %d (%d) searches, want %d", len(repo.results), repo.resultOrder.Len(), resultCacheSize)
+	}
+	if _, ok := repo.results[searchKey(Literal("apply"))]; ok {
+		t.Error("expected the least recently used search to be dropped")
+	}
+	if _, ok := repo.results[searchKey(Literal(fmt.Sprintf("missing_%d", 2*resultCacheSize-1)))]; !ok {
+		t.Error("expected the most recent search to be cached")
+	}
+}
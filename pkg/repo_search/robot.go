@@ -0,0 +1,291 @@
+package repo_search
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	robotSectionPattern = regexp.MustCompile(`^\*+\s*(?P<name>[^*]+?)\s*\**\s*$`)
+	// Cells are separated by 2 or more spaces, a tab or a pipe
+	robotCellSeparator = regexp.MustCompile(`\s{2,}|\t|\s+\|\s+`)
+)
+
+// Names of robot settings that can appear at the start of a line in the settings section
+var robotSettings = map[string]bool{
+	"library":        true,
+	"resource":       true,
+	"variables":      true,
+	"documentation":  true,
+	"metadata":       true,
+	"suite setup":    true,
+	"suite teardown": true,
+	"test setup":     true,
+	"test teardown":  true,
+	"test template":  true,
+	"test timeout":   true,
+	"task setup":     true,
+	"task teardown":  true,
+	"task template":  true,
+	"task timeout":   true,
+	"force tags":     true,
+	"default tags":   true,
+	"test tags":      true,
+	"keyword tags":   true,
+}
+
+type robotSection int
+
+const (
+	robotNoSection robotSection = iota
+	robotSettingsSection
+	robotVariablesSection
+	robotTestCasesSection
+	robotKeywordsSection
+	robotCommentsSection
+)
+
+// robotBlock A test case or keyword definition (name line + its body)
+type robotBlock struct {
+	section robotSection
+	name    string
+	start   int
+	end     int
+}
+
+// Robot Robot Framework test suites and resource files
+type Robot struct{}
+
+func (Robot) Name() string {
+	return "robot"
+}
+
+func (Robot) Extensions() []string {
+	return []string{".robot", ".resource"}
+}
+
+// DeclarationName Keyword and test case names are the only non indented lines
+// (apart from section headers, settings, variables and comments). Only the
+// ones in the keywords section are declarations (see IsDeclarationSection).
+func (Robot) DeclarationName(t ContainerType, line string) string {
+	if t != MethodContainer {
+		return ""
+	}
+
+	line = strings.TrimRight(line, "\r")
+	if line == "" || strings.ContainsAny(line[:1], " \t*#$@&%[|.") {
+		return ""
+	}
+
+	name := robotCellSeparator.Split(line, 2)[0]
+	if robotSettings[strings.ToLower(name)] {
+		return ""
+	}
+	return name
+}
+
+// IsDeclarationSection Keywords can only be declared in the keywords section
+// (the names in the test cases section are TCs)
+func (Robot) IsDeclarationSection(lines []string, idx int) bool {
+	for i := idx - 1; i >= 0; i-- {
+		match := robotSectionPattern.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if match != nil {
+			return robotSectionFromHeader(match[robotSectionPattern.SubexpIndex("name")]) == robotKeywordsSection
+		}
+	}
+	return false
+}
+
+// UsagePattern Robot Framework treats keyword names as equal regardless of
+// case, spaces and underscores -> `set_heater_power` also matches `Set Heater Power`.
+func (Robot) UsagePattern(name string) *regexp.Regexp {
+	normalized := robotNormalizeName(name)
+	chars := []string{}
+	for _, c := range normalized {
+		chars = append(chars, regexp.QuoteMeta(string(c)))
+	}
+	return regexp.MustCompile(`(?i)\b` + strings.Join(chars, `[ _]?`) + `\b`)
+}
+
+// EnclosingScope Only user keywords can be searched for. A match inside a
+// test case is not enclosed by anything searchable.
+func (Robot) EnclosingScope(pretext, _ string) Scope {
+	blocks := robotBlocks(pretext)
+	if len(blocks) == 0 {
+		return Scope{}
+	}
+
+	// Pretext ends inside the last block (if the match is in a block at all)
+	last := blocks[len(blocks)-1]
+	if last.section != robotKeywordsSection || last.end < len(pretext) {
+		return Scope{}
+	}
+	return Scope{Name: last.name}
+}
+
+// BlockEnd The body of a keyword ends before the next non indented line
+// (trailing empty lines are not part of it)
+func (Robot) BlockEnd(lines []string, defIdx int) int {
+	end := defIdx
+	for i := defIdx + 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if line == "" {
+			continue
+		}
+		if !strings.ContainsAny(line[:1], " \t#") {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+func (Robot) IsGenerated(_ string) bool {
+	return false
+}
+
+func (Robot) IsTcFile(path string) bool {
+	path = filepath.ToSlash(path)
+	return strings.Contains(path, "test_cases/") && strings.HasSuffix(path, ".robot")
+}
+
+// TestCaseTexts A robot suite contains many TCs. A match in a TC belongs only to
+// that TC while a match in the settings or variables belongs to all of them.
+func (Robot) TestCaseTexts(text string, pos int) []string {
+	blocks := robotBlocks(text)
+	for _, block := range blocks {
+		if block.start > pos {
+			break
+		}
+		if pos < block.end {
+			if block.section == robotTestCasesSection {
+				return []string{text[block.start:block.end]}
+			}
+			return nil
+		}
+	}
+
+	// Match is outside of any block -> check which section it is in
+	section := robotNoSection
+	for _, header := range robotSectionHeaders(text) {
+		if header.start > pos {
+			break
+		}
+		section = header.section
+	}
+	if section != robotSettingsSection && section != robotVariablesSection {
+		return nil
+	}
+
+	tcTexts := []string{}
+	for _, block := range blocks {
+		if block.section == robotTestCasesSection {
+			tcTexts = append(tcTexts, text[block.start:block.end])
+		}
+	}
+	return tcTexts
+}
+
+// TestCaseRanges Settings and variables belong to all TCs of the suite
+func (Robot) TestCaseRanges(text string) (testCases, shared [][]int) {
+	for _, block := range robotBlocks(text) {
+		if block.section == robotTestCasesSection {
+			testCases = append(testCases, []int{block.start, block.end})
+		}
+	}
+
+	headers := robotSectionHeaders(text)
+	for i, header := range headers {
+		if header.section != robotSettingsSection && header.section != robotVariablesSection {
+			continue
+		}
+		end := len(text)
+		if i+1 < len(headers) {
+			end = headers[i+1].start
+		}
+		shared = append(shared, []int{header.start, end})
+	}
+	return testCases, shared
+}
+
+func robotNormalizeName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, " ", "")
+	name = strings.ReplaceAll(name, "_", "")
+	return name
+}
+
+func robotSectionFromHeader(header string) robotSection {
+	header = strings.ToLower(header)
+	switch {
+	case strings.HasPrefix(header, "setting"):
+		return robotSettingsSection
+	case strings.HasPrefix(header, "variable"):
+		return robotVariablesSection
+	case strings.HasPrefix(header, "test case"), strings.HasPrefix(header, "task"):
+		return robotTestCasesSection
+	case strings.HasPrefix(header, "keyword"):
+		return robotKeywordsSection
+	case strings.HasPrefix(header, "comment"):
+		return robotCommentsSection
+	}
+	return robotNoSection
+}
+
+// robotSectionHeaders Returns section headers as blocks without name/end
+func robotSectionHeaders(text string) []robotBlock {
+	headers := []robotBlock{}
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		match := robotSectionPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match != nil {
+			section := robotSectionFromHeader(match[robotSectionPattern.SubexpIndex("name")])
+			headers = append(headers, robotBlock{section: section, start: offset})
+		}
+		offset += len(line)
+	}
+	return headers
+}
+
+// robotBlocks Splits robot text into test case and keyword definitions. The end
+// of the last block is the end of the text.
+func robotBlocks(text string) []robotBlock {
+	blocks := []robotBlock{}
+	section := robotNoSection
+
+	closeLast := func(end int) {
+		if len(blocks) > 0 && blocks[len(blocks)-1].end == -1 {
+			blocks[len(blocks)-1].end = end
+		}
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		lineStart := offset
+		offset += len(line)
+
+		if match := robotSectionPattern.FindStringSubmatch(trimmed); match != nil {
+			closeLast(lineStart)
+			section = robotSectionFromHeader(match[robotSectionPattern.SubexpIndex("name")])
+			continue
+		}
+
+		if section != robotTestCasesSection && section != robotKeywordsSection {
+			continue
+		}
+
+		// Indented, empty and comment lines belong to the current block
+		if trimmed == "" || strings.ContainsAny(trimmed[:1], " \t#") {
+			continue
+		}
+
+		closeLast(lineStart)
+		name := robotCellSeparator.Split(trimmed, 2)[0]
+		blocks = append(blocks, robotBlock{section: section, name: name, start: lineStart, end: -1})
+	}
+	closeLast(len(text))
+
+	return blocks
+}
@@ -0,0 +1,198 @@
+package repo_search
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+const robotSuite = `*** Settings ***
+Resource    heater.resource
+
+*** Variables ***
+${POWER}    10
+
+*** Test Cases ***
+Heater Power
+    [Tags]    4AP2-1001
+    Set Power    ${POWER}
+
+# comment between test cases
+Heater Off
+    Set Power    0
+
+*** Keywords ***
+Set Power
+    [Arguments]    ${value}
+    Power    ${value}
+`
+
+func TestRobotBlocks(t *testing.T) {
+	want := []struct {
+		section robotSection
+		name    string
+		first   string
+		last    string
+	}{
+		{robotTestCasesSection, "Heater Power", "Heater Power", "# comment between test cases"},
+		{robotTestCasesSection, "Heater Off", "Heater Off", "    Set Power    0"},
+		{robotKeywordsSection, "Set Power", "Set Power", "    Power    ${value}"},
+	}
+
+	blocks := robotBlocks(robotSuite)
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), len(want))
+	}
+
+	for i, block := range blocks {
+		lines := strings.Split(strings.TrimRight(robotSuite[block.start:block.end], "\n"), "\n")
+		if block.section != want[i].section || block.name != want[i].name {
+			t.Errorf("block %d = %d %q, want %d %q", i, block.section, block.name, want[i].section, want[i].name)
+		}
+		if lines[0] != want[i].first || lines[len(lines)-1] != want[i].last {
+			t.Errorf("block %d spans %q..%q, want %q..%q", i, lines[0], lines[len(lines)-1], want[i].first, want[i].last)
+		}
+	}
+
+	if blocks[len(blocks)-1].end != len(robotSuite) {
+		t.Errorf("last block ends at %d, want end of text %d", blocks[len(blocks)-1].end, len(robotSuite))
+	}
+}
+
+func TestRobotBlocksCrlf(t *testing.T) {
+	text := strings.ReplaceAll(robotSuite, "\n", "\r\n")
+	blocks := robotBlocks(text)
+	if len(blocks) != 3 || blocks[1].name != "Heater Off" {
+		t.Fatalf("unexpected blocks %+v", blocks)
+	}
+}
+
+func TestRobotTestCaseTexts(t *testing.T) {
+	tests := []struct {
+		name  string
+		match string
+		want  []string
+	}{
+		{"in test case", "Set Power    0", []string{"Heater Off"}},
+		{"in keyword", "Power    ${value}", nil},
+		{"in settings", "heater.resource", []string{"Heater Power", "Heater Off"}},
+		{"in variables", "${POWER}    10", []string{"Heater Power", "Heater Off"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := strings.Index(robotSuite, tt.match)
+			texts := Robot{}.TestCaseTexts(robotSuite, pos)
+
+			names := []string{}
+			for _, text := range texts {
+				names = append(names, strings.SplitN(text, "\n", 2)[0])
+			}
+			if len(tt.want) == 0 && len(names) == 0 {
+				return
+			}
+			if !equalStrings(names, tt.want) {
+				t.Errorf("test cases = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotDeclarationMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		match string
+		want  bool
+	}{
+		{"test case name", "Heater Power", false},
+		{"keyword name", "Set Power\n", true},
+		{"keyword call", "Set Power    0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := strings.Index(robotSuite, tt.match)
+			result := ProcessMatch([]int{pos, pos + len("Set Power")}, robotSuite, Robot{}, MatchContext{})
+			if result.isMethodDecl != tt.want {
+				t.Errorf("isMethodDecl = %v, want %v", result.isMethodDecl, tt.want)
+			}
+		})
+	}
+}
+
+// sortedTcIds Returns the sorted IDs of testCases
+func sortedTcIds(testCases TestCasesMap) []string {
+	ids := []string{}
+	for _, tc := range SortedTcs(testCases) {
+		ids = append(ids, tc.info.id)
+	}
+	return ids
+}
+
+func TestRobotTestCaseNamedLikeKeyword(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/heater.resource": "*** Keywords ***\nSet Power\n    [Arguments]    ${value}\n    Apply Power    ${value}\n",
+		"test_cases/heater/heater.robot": `*** Settings ***
+Resource    ../../lib/heater.resource
+
+*** Test Cases ***
+Set Power Limits
+    [Documentation]    Polarion ID: 4AP2-1001
+    ...    Setup: Bench A
+    Set Power    10
+`,
+	})
+	repo.Output = io.Discard
+
+	// The TC name is not a second declaration of the keyword
+	testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("Apply Power"), 3)
+	if got := sortedTcIds(testCases); !equalStrings(got, []string{"4AP2-1001"}) {
+		t.Errorf("found TCs %q, want 4AP2-1001", got)
+	}
+}
+
+func TestRobotPythonKeywordName(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/HeaterLibrary.py": `from robot.api.deco import keyword
+
+class HeaterLibrary:
+    @keyword("Heat Up To")
+    def set_temperature(self, temperature):
+        HEATER_IMPL(temperature)
+
+    def cool(self):
+        COOLER_IMPL()
+`,
+		"test_cases/heater/heater.robot": `*** Settings ***
+Library    ../../lib/HeaterLibrary.py
+
+*** Test Cases ***
+Heater Temperature
+    [Documentation]    Polarion ID: 4AP2-1001
+    ...    Setup: Bench A
+    Heat Up To    40
+
+Cooler
+    [Documentation]    Polarion ID: 4AP2-1002
+    ...    Setup: Bench A
+    Cool
+`,
+	})
+	repo.Output = io.Discard
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"HEATER_IMPL", []string{"4AP2-1001"}},
+		{"COOLER_IMPL", []string{"4AP2-1002"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal(tt.pattern), 3)
+			if got := sortedTcIds(testCases); !equalStrings(got, tt.want) {
+				t.Errorf("found TCs %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,60 @@ package repo_search
 import (
 	"fmt"
 	"log"
-	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-var AlreadySearched map[string]bool
+// SearchedSet Keys of the symbols, fixtures and modules that were already
+// searched during one (recursive) search. Every search needs its own set.
+type SearchedSet map[string]bool
+
+// Symbol Method (or keyword) to search usages of. How a usage looks like
+// depends on the language of the searched file.
+type Symbol struct {
+	Name string
+	// Class Class that declares the method ("" for functions)
+	Class string
+	// DefinedIn File that declares the symbol
+	DefinedIn string
+	// Kind Constructors are used through instantiations of their class
+	Kind ScopeKind
+	// Keyword Name of the method in robot files if it differs from Name
+	Keyword string
+	// names Python file -> names under which the symbol is available in that
+	// file (imports and aliases). If nil the symbol is searched in every file.
+	names map[string][]string
+	// hierarchy Files of the declaring class and its subclasses. Overrides of
+	// the method are expected there and are not duplicate declarations.
+	hierarchy map[string]bool
+}
 
-type SearchTerm interface {
-	string | *regexp.Regexp
+func (s Symbol) String() string {
+	return s.Name
+}
+
+// Key Identifies the symbol in a SearchedSet
+func (s Symbol) Key() string {
+	if s.Kind == ConstructorScope {
+		return fmt.Sprintf("%s:%s.%s", s.DefinedIn, s.Class, constructorName(s.DefinedIn))
+	}
+	return fmt.Sprintf("%s:%s.%s", s.DefinedIn, s.Class, s.Name)
+}
+
+// NamesIn Returns the names under which the symbol can be used in file.
+// Module files (i.e. python) which don't import the symbol's module return none.
+// Other files use the keyword name of the symbol (if it has one).
+func (s Symbol) NamesIn(file string) []string {
+	_, isModule := LanguageForFile(file).(ModuleLanguage)
+	if !isModule && s.Keyword != "" {
+		return []string{s.Keyword}
+	}
+	if s.names == nil || !isModule {
+		return []string{s.Name}
+	}
+	return s.names[file]
 }
 
 const (
@@ -22,22 +66,32 @@ const (
 )
 
 type TestCase struct {
-	path string
-	info TestCaseInfo
+	path    string
+	info    TestCaseInfo
+	matches []SearchResult
+	// Search terms (from the original pattern to the last containing method)
+	// that lead to this TC
+	chain []string
 }
 
-func (t *TestCase) Protocol() string {
-	// Format test case path to expected test script reference path url
+// ScriptPath Returns the TC path relative to the test_automation folder (i.e. test_cases/...)
+func (t *TestCase) ScriptPath() string {
 	_, after, found := strings.Cut(t.path, "test_cases")
 	if !found {
 		errorTxt := fmt.Sprintf("Couldn't find '/test_cases/' in path: %s", t.path)
 		log.Fatalf(ErrorStyle.Render(errorTxt))
 	}
 	tcPath := "test_cases" + after
-	tcPath = strings.ReplaceAll(tcPath, "\\", "/")
+	return strings.ReplaceAll(tcPath, "\\", "/")
+}
 
-	testScriptUrl := fmt.Sprintf(ScriptUrlTemplate, tcPath)
-	testScriptReference := fmt.Sprintf(ScriptReferenceTemplate, testScriptUrl)
+func (t *TestCase) ScriptUrl() string {
+	return fmt.Sprintf(ScriptUrlTemplate, t.ScriptPath())
+}
+
+func (t *TestCase) Protocol() string {
+	// Format test case path to expected test script reference path url
+	testScriptReference := fmt.Sprintf(ScriptReferenceTemplate, t.ScriptUrl())
 
 	tcInfo := fmt.Sprintf("Duration: %s; Setup: %s", t.info.estimate, t.info.setup)
 	out := fmt.Sprintf(ProtocolTemplate, t.info.id, tcInfo, testScriptReference)
@@ -99,14 +153,51 @@ func (m TestCasesMap) String() string {
 
 func UpdateMap(v1, v2 TestCasesMap) TestCasesMap {
 	for k, v := range v2 {
+		// Same TC can be reached through different search paths -> keep all matches
+		// but only the chain through which it was found first
+		if existing, ok := v1[k]; ok {
+			// Copy so that the matches of existing and v don't share a backing array
+			matches := make([]SearchResult, 0, len(existing.matches)+len(v.matches))
+			matches = append(matches, existing.matches...)
+			v.matches = append(matches, v.matches...)
+			v.chain = existing.chain
+		}
 		v1[k] = v
 	}
 	return v1
 }
 
-func SearchForUsagesInTc[T SearchTerm](
-	dir, fileType string,
-	searchPattern T,
+// AddTcMatch Adds TC to testCases (if not already there) and records match
+// (if any) as one of its matches. searchTerm is the term that found the TC.
+func AddTcMatch(testCases TestCasesMap, path string, info TestCaseInfo, match *SearchResult, searchTerm string) {
+	tc, ok := testCases[info.id]
+	if !ok {
+		tc = TestCase{
+			path:  path,
+			info:  info,
+			chain: []string{searchTerm},
+		}
+	}
+	if match != nil {
+		tc.matches = append(tc.matches, *match)
+	}
+	testCases[info.id] = tc
+}
+
+// ExtendChain Prepends the search term that lead to the recursive search
+// which found testCases to the chain of each TC
+func ExtendChain(testCases TestCasesMap, searchTerm string) TestCasesMap {
+	for id, tc := range testCases {
+		tc.chain = append([]string{searchTerm}, tc.chain...)
+		testCases[id] = tc
+	}
+	return testCases
+}
+
+func SearchForUsagesInTc(
+	repo *Repo,
+	alreadySearched SearchedSet,
+	searchPattern Matcher,
 	degreesOfSeparation int,
 ) TestCasesMap {
 	testCases := TestCasesMap{}
@@ -122,17 +213,29 @@ func SearchForUsagesInTc[T SearchTerm](
 	if degreesOfSeparation <= 0 {
 		return TestCasesMap{}
 	}
+	repo.traceTerm(searchPattern)
 
 	methodDeclarationNum := 0
 
-	results := SearchInRepo(dir, fileType, searchPattern)
+	results := SearchInRepo(repo, searchPattern)
 	for _, result := range results {
-		for idx, match := range result.matches {
+		// Declarations count as well (i.e. a removed duplicate declaration)
+		if len(result.matches) > 0 {
+			repo.traceFiles(result.file)
+		}
+		usages := []SearchResult{}
+		for _, match := range result.matches {
+			if isClassDeclaration(searchPattern, match) {
+				continue
+			}
 			if !match.isMethodDecl {
+				usages = append(usages, match)
 				continue
 			}
 
-			methodDeclarationNum++
+			if !isExpectedDeclaration(searchPattern, result.file) {
+				methodDeclarationNum++
+			}
 			// If we find search results that result in multiple method declarations
 			// we can't reliably use the result from the search cause our search term
 			// is not unique -> return no results
@@ -144,24 +247,32 @@ func SearchForUsagesInTc[T SearchTerm](
 				log.Println(WarningStyle.Render(errorTxt))
 				return TestCasesMap{}
 			}
-
-			// Remove any declaration match from results so that we don't
-			// consider it as a nonTcMatch
-			result.RemoveMatch(idx)
 		}
+		// Remove any declaration match from results so that we don't
+		// consider it as a nonTcMatch
+		result.matches = usages
 
 		if len(result.matches) == 0 {
 			continue
 		}
 
-		fmt.Println(result)
-		if result.tcInfo.id != "" {
-			testCases[result.tcInfo.id] = TestCase{
-				path: result.file,
-				info: result.tcInfo,
+		fmt.Fprintln(repo.Output, result)
+		repo.record(fmt.Sprint(searchPattern), result.matches...)
+		nonTcResult := result
+		nonTcResult.matches = nil
+		for _, match := range result.matches {
+			if len(match.testCases) == 0 {
+				nonTcResult.matches = append(nonTcResult.matches, match)
+				continue
+			}
+
+			for _, info := range match.testCases {
+				AddTcMatch(testCases, result.file, info, &match, fmt.Sprint(searchPattern))
 			}
-		} else {
-			nonTcMatches = append(nonTcMatches, result)
+		}
+
+		if len(nonTcResult.matches) > 0 {
+			nonTcMatches = append(nonTcMatches, nonTcResult)
 		}
 	}
 
@@ -174,7 +285,22 @@ func SearchForUsagesInTc[T SearchTerm](
 	)
 	for _, fileResult := range nonTcMatches {
 		for _, searchResult := range fileResult.matches {
-			if searchResult.usedInMethod == "" {
+			if searchResult.usedIn.Kind == ModuleScope && searchResult.usedIn.Name == "" {
+				moduleKey := ModuleSearchKey(searchResult.file)
+				if _, ok := alreadySearched[moduleKey]; ok {
+					continue
+				}
+
+				infoTxt := fmt.Sprintf("Extending search for %v by importers of %s", searchPattern, searchResult.file)
+				log.Print(InfoStyle.Render(infoTxt))
+
+				alreadySearched[moduleKey] = true
+				foundTcs := SearchForModuleImporters(repo, alreadySearched, searchResult.file, degreesOfSeparation-1)
+				testCases = UpdateMap(testCases, ExtendChain(foundTcs, fmt.Sprint(searchPattern)))
+				continue
+			}
+
+			if searchResult.usedIn.Name == "" {
 				errorTxt := fmt.Sprintf(
 					"No containing method found for match:\n%s\n%d: %s",
 					searchResult.file,
@@ -185,17 +311,34 @@ func SearchForUsagesInTc[T SearchTerm](
 				continue
 			}
 
-			// Add word boudary to make sure we search for exact word matches
-			newSearchTerm := fmt.Sprintf("\\b%s\\b", searchResult.usedInMethod)
-			newSearchPattern, err := regexp.Compile(newSearchTerm)
-			if err != nil {
-				errorTxt := fmt.Sprint("Couldn't compile method pattern regexp for: ", newSearchTerm)
-				log.Fatalln(ErrorStyle.Render(errorTxt))
+			if isCoveredOverride(searchPattern, searchResult) {
+				continue
 			}
 
-			if _, ok := AlreadySearched[newSearchTerm]; ok {
+			if searchResult.usedIn.Kind == FixtureScope {
+				fixtureKey := FixtureSearchKey(searchResult.file, searchResult.usedIn)
+				if _, ok := alreadySearched[fixtureKey]; ok {
+					continue
+				}
+
+				infoTxt := fmt.Sprintf("Extending search for %v by fixture %s", searchPattern, searchResult.usedIn.Name)
+				log.Print(InfoStyle.Render(infoTxt))
+
+				alreadySearched[fixtureKey] = true
+				foundTcs := SearchForFixtureUsages(
+					repo, alreadySearched, searchResult.file, searchResult.usedIn, degreesOfSeparation-1,
+				)
+				testCases = UpdateMap(testCases, ExtendChain(foundTcs, fmt.Sprint(searchPattern)))
+				continue
+			}
+
+			// Each searched file's language and imports decide how a usage of the method looks like
+			newSearchPattern := repo.Symbol(searchResult.usedIn, searchResult.file)
+			newSearchTerm := newSearchPattern.Key()
+
+			if _, ok := alreadySearched[newSearchTerm]; ok {
 				/* NOTE: this spams too much
-				warningTxt := fmt.Sprint("Containing method already searched: ", searchResult.usedInMethod)
+				warningTxt := fmt.Sprint("Containing method already searched: ", searchResult.usedIn.Name)
 				log.Println(WarningStyle.Render(warningTxt))
 				*/
 				continue
@@ -204,27 +347,70 @@ func SearchForUsagesInTc[T SearchTerm](
 			infoTxt := fmt.Sprintf("Extending search for %v by %s", searchPattern, newSearchPattern)
 			log.Print(InfoStyle.Render(infoTxt))
 
-			// We just track the search term and not the regexp pattern for simplicity
-			AlreadySearched[newSearchTerm] = true
-			foundTcs := SearchForUsagesInTc(dir, fileType, newSearchPattern, degreesOfSeparation-1)
-			testCases = UpdateMap(testCases, foundTcs)
+			alreadySearched[newSearchTerm] = true
+			foundTcs := SearchForUsagesInTc(repo, alreadySearched, newSearchPattern, degreesOfSeparation-1)
+			testCases = UpdateMap(testCases, ExtendChain(foundTcs, fmt.Sprint(searchPattern)))
 		}
 	}
 
 	return testCases
 }
 
-func SearchInRepo[T SearchTerm](dir, fileType string, searchPattern T) []FileResult {
-	files, err := GetFilesFromDir(dir, fileType)
-	if err != nil {
-		errorTxt := fmt.Sprintf("Couldn't get list of files for dir %s: %v", dir, err)
-		log.Fatalf(errorTxt)
+// SearchForScopeUsages Finds the TCs that reach scope (declared in file), the
+// same way as for a match inside of scope
+func SearchForScopeUsages(
+	repo *Repo,
+	alreadySearched SearchedSet,
+	file string,
+	scope Scope,
+	degreesOfSeparation int,
+) TestCasesMap {
+	switch {
+	case scope.Kind == ModuleScope && scope.Name == "":
+		alreadySearched[ModuleSearchKey(file)] = true
+		return SearchForModuleImporters(repo, alreadySearched, file, degreesOfSeparation)
+	case scope.Name == "":
+		return TestCasesMap{}
+	case scope.Kind == FixtureScope:
+		alreadySearched[FixtureSearchKey(file, scope)] = true
+		return SearchForFixtureUsages(repo, alreadySearched, file, scope, degreesOfSeparation)
+	}
+
+	symbol := repo.Symbol(scope, file)
+	alreadySearched[symbol.Key()] = true
+	return SearchForUsagesInTc(repo, alreadySearched, symbol, degreesOfSeparation)
+}
+
+// isCoveredOverride A match inside of an override of the searched method that is
+// in the class hierarchy (i.e. a super() call) is already covered by the search
+func isCoveredOverride(searchPattern Matcher, match SearchResult) bool {
+	symbol, ok := searchPattern.(Symbol)
+	return ok && match.usedIn.Name == symbol.Name && symbol.hierarchy[match.file]
+}
+
+// isClassDeclaration Instantiations of a class look like its declaration
+// (`class Sub(Class):`), which is neither a usage nor a method declaration
+func isClassDeclaration(searchPattern Matcher, match SearchResult) bool {
+	symbol, ok := searchPattern.(Symbol)
+	if !ok || symbol.Kind != ConstructorScope {
+		return false
 	}
+	return LanguageForFile(match.file).DeclarationName(ClassContainer, match.matchLineTxt) != ""
+}
+
+// isExpectedDeclaration Overrides of a method in subclasses are not duplicate declarations
+func isExpectedDeclaration(searchPattern Matcher, file string) bool {
+	symbol, ok := searchPattern.(Symbol)
+	return ok && symbol.hierarchy[file]
+}
+
+func SearchInRepo(repo *Repo, searchPattern Matcher) []FileResult {
+	files := repo.files
 
 	var (
 		workerNum = 4
 		jobNum    = len(files)
-		jobs      = make(chan SearchJob[T], jobNum)
+		jobs      = make(chan SearchJob, jobNum)
 		results   = make(chan FileResult)
 		done      = make(chan int, workerNum)
 	)
@@ -234,7 +420,8 @@ func SearchInRepo[T SearchTerm](dir, fileType string, searchPattern T) []FileRes
 	}
 
 	for _, file := range files {
-		jobs <- SearchJob[T]{
+		jobs <- SearchJob{
+			repo:     repo,
 			filepath: file,
 			pattern:  searchPattern,
 		}
@@ -260,24 +447,38 @@ func SearchInRepo[T SearchTerm](dir, fileType string, searchPattern T) []FileRes
 		}
 	}
 
+	// Workers finish in any order -> sort so that repeated searches (i.e. of
+	// different revisions) find TCs through the same chains
+	sort.Slice(fileResults, func(i, j int) bool {
+		return fileResults[i].file < fileResults[j].file
+	})
 	return fileResults
 }
 
-func SearchFile[T SearchTerm](path string, pattern T) *FileResult {
+func SearchFile(repo *Repo, path string, pattern Matcher) *FileResult {
 	results := []SearchResult{}
 
-	data, err := os.ReadFile(path)
+	text, err := repo.ReadText(path)
 	if err != nil {
 		errorTxt := fmt.Sprintf("ERROR: Couldn't read file %s: %v", path, err)
 		log.Print(ErrorStyle.Render(errorTxt))
 		return nil
 	}
-	text := string(data)
+	lang := LanguageForFile(path)
+	relPath := repo.RelPath(path)
+
+	isTc := lang.IsTcFile(path)
+	tcInfos := map[string]TestCaseInfo{}
 
-	matches := FindAllStringIndex(text, pattern)
+	matches := pattern.FindAll(text, lang, path)
 	for _, match := range matches {
-		searchResult := ProcessMatch(match, text)
+		searchResult := ProcessMatch(match, text, lang, repo.Context)
 		searchResult.file = path
+		searchResult.relPath = relPath
+
+		if isTc {
+			searchResult.testCases = TestCasesAt(lang, text, path, match[0], tcInfos)
+		}
 
 		results = append(results, searchResult)
 	}
@@ -286,28 +487,41 @@ func SearchFile[T SearchTerm](path string, pattern T) *FileResult {
 		return nil
 	}
 
-	isTc := IsPathTc(path)
-	var tcInfo TestCaseInfo
-	if isTc {
-		tcInfo = ProcessTc(text, path)
-	}
 	return &FileResult{
 		file:    path,
+		relPath: relPath,
 		matches: results,
 		isTc:    isTc,
-		tcInfo:  tcInfo,
 	}
 }
 
-type SearchJob[T SearchTerm] struct {
+// TestCasesAt Returns info of the TCs affected by a match at pos in a TC file.
+// tcInfos maps TC text to extracted info so that each TC is only processed once.
+func TestCasesAt(lang Language, text, path string, pos int, tcInfos map[string]TestCaseInfo) []TestCaseInfo {
+	infos := []TestCaseInfo{}
+	for _, tcText := range lang.TestCaseTexts(text, pos) {
+		info, ok := tcInfos[tcText]
+		if !ok {
+			info = ProcessTc(tcText, path)
+			tcInfos[tcText] = info
+		}
+		if info.id != "" {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+type SearchJob struct {
+	repo     *Repo
 	filepath string
-	pattern  T
+	pattern  Matcher
 }
 
-func worker[T SearchTerm](jobs <-chan SearchJob[T], results chan<- FileResult, done chan<- int) {
+func worker(jobs <-chan SearchJob, results chan<- FileResult, done chan<- int) {
 	numOfResults := 0
 	for j := range jobs {
-		found := SearchFile(j.filepath, j.pattern)
+		found := j.repo.cachedSearch(j.filepath, j.pattern)
 		if found == nil {
 			continue
 		}
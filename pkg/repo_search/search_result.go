@@ -1,24 +1,96 @@
 package repo_search
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
 
 type SearchResult struct {
-	file         string
-	line         int
-	col          int
-	colEnd       int
+	file string
+	// relPath Path of file relative to its root (used for reporting)
+	relPath string
+	line    int
+	// lineEnd Last line of the match (regex matches can span multiple lines)
+	lineEnd int
+	col     int
+	colEnd  int
+	// matchLineTxt Full lines of the match
 	matchLineTxt string
-	usedInMethod string
+	// Context lines before and after the match lines
+	before       []string
+	after        []string
+	usedIn       Scope
 	isMethodDecl bool
+	// TCs (from a TC file) that contain the match
+	testCases []TestCaseInfo
 }
 
 func (r SearchResult) String() string {
-	// Apply styling to line number and match
-	out := MatchStyle.Render(fmt.Sprintf("%d: ", r.line))
-	out += r.matchLineTxt[:r.col]
-	out += MatchStyle.Render(r.matchLineTxt[r.col:r.colEnd])
-	out += r.matchLineTxt[r.colEnd:]
+	lines := []string{}
+	// Context lines are marked like in grep output
+	for i, txt := range r.before {
+		lines = append(lines, fmt.Sprintf("%d- %s", r.line-len(r.before)+i, txt))
+	}
+
+	offset := 0
+	for i, txt := range strings.Split(r.matchLineTxt, "\n") {
+		// Apply styling to line number and the part of the match on this line
+		from := clampIdx(r.col-offset, len(txt))
+		to := clampIdx(r.colEnd-offset, len(txt))
+
+		out := MatchStyle.Render(fmt.Sprintf("%d: ", r.line+i))
+		out += txt[:from]
+		out += MatchStyle.Render(txt[from:to])
+		out += txt[to:]
+		lines = append(lines, out)
+
+		offset += len(txt) + 1
+	}
+
+	for i, txt := range r.after {
+		lines = append(lines, fmt.Sprintf("%d- %s", r.lineEnd+1+i, txt))
+	}
 
 	// return fmt.Sprintf("%d: %s", r.line, r.matchLineTxt)
-	return out
+	return strings.Join(lines, "\n")
+}
+
+func clampIdx(idx, length int) int {
+	if idx < 0 {
+		return 0
+	}
+	if idx > length {
+		return length
+	}
+	return idx
+}
+
+// RecordedResult Search result together with the term whose search found it
+type RecordedResult struct {
+	SearchTerm string
+	result     SearchResult
+}
+
+// ResultRecorder Collects the results of a search (incl. the ones outside of
+// TCs and the ones of its recursive searches) in the order they are found
+type ResultRecorder struct {
+	mu      sync.Mutex
+	results []RecordedResult
+}
+
+func (r *ResultRecorder) Record(searchTerm string, result SearchResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, RecordedResult{SearchTerm: searchTerm, result: result})
+}
+
+// Results Returns the recorded results (none if r is nil)
+func (r *ResultRecorder) Results() []RecordedResult {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedResult{}, r.results...)
 }
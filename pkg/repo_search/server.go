@@ -0,0 +1,161 @@
+package repo_search
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Server Answers search requests over HTTP from a repo that is only built
+// once. Changed files are picked up by Refresh.
+type Server struct {
+	Repo *Repo
+	// Template Verification loop template used for XML responses
+	Template  string
+	WorkItems WorkItems
+	// Degrees Default levels of recursive search
+	Degrees int
+
+	// Searches run concurrently, refreshing the repo needs exclusive access
+	mu sync.RWMutex
+}
+
+// SearchResponse Body of JSON responses
+type SearchResponse struct {
+	Pattern   string   `json:"pattern"`
+	TestCases []TcJson `json:"testCases"`
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	// /search?pattern=<pattern>&match=<kind>&dist=<degrees>&format=<json|xml>
+	mux.HandleFunc("/search", s.handleSearch)
+	// /symbol?file=<file>&name=<method>&class=<class>&dist=<degrees>&format=<json|xml>
+	mux.HandleFunc("/symbol", s.handleSymbol)
+	return mux
+}
+
+// RefreshEvery Refreshes the repo every interval until the process is stopped
+func (s *Server) RefreshEvery(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		if changed := s.Refresh(); len(changed) > 0 {
+			infoTxt := fmt.Sprintf("Refreshed %d changed files", len(changed))
+			log.Println(InfoStyle.Render(infoTxt))
+		}
+	}
+}
+
+// Refresh Picks up the changed files of the repo once the running searches
+// are done. Returns the changed files.
+func (s *Server) Refresh() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Repo.Refresh()
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pattern := query.Get("pattern")
+	if pattern == "" {
+		http.Error(w, "missing pattern", http.StatusBadRequest)
+		return
+	}
+
+	kind := query.Get("match")
+	if kind == "" {
+		kind = "literal"
+	}
+	matcher, err := NewMatcher(kind, pattern)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	degrees, err := s.degrees(query.Get("dist"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	testCases := SearchForUsagesInTc(s.Repo, SearchedSet{}, matcher, degrees)
+	s.mu.RUnlock()
+
+	s.respond(w, query.Get("format"), matcher.String(), testCases)
+}
+
+func (s *Server) handleSymbol(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name, class := query.Get("name"), query.Get("class")
+	if name == "" || query.Get("file") == "" {
+		http.Error(w, "missing file or name", http.StatusBadRequest)
+		return
+	}
+
+	degrees, err := s.degrees(query.Get("dist"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, ok := s.Repo.FindFile(query.Get("file"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown file %s", query.Get("file")), http.StatusNotFound)
+		return
+	}
+
+	scope := Scope{Name: name, Kind: MethodScope, Class: class}
+	if constructor := constructorName(file); constructor != "" && name == constructor {
+		if class == "" {
+			http.Error(w, fmt.Sprintf("missing class of %s", constructor), http.StatusBadRequest)
+			return
+		}
+		// Constructors are searched by the name of their class
+		scope = Scope{Name: class, Kind: ConstructorScope, Class: class}
+	}
+
+	testCases := SearchForScopeUsages(s.Repo, SearchedSet{}, file, scope, degrees)
+
+	searchTxt := fmt.Sprintf("%s:%s.%s", s.Repo.RelPath(file), class, name)
+	s.respond(w, query.Get("format"), searchTxt, testCases)
+}
+
+func (s *Server) degrees(value string) (int, error) {
+	if value == "" {
+		return s.Degrees, nil
+	}
+	degrees, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid dist %q: %w", value, err)
+	}
+	return degrees, nil
+}
+
+// respond Writes the found TCs as JSON or as verification loop XML (format xml)
+func (s *Server) respond(w http.ResponseWriter, format, searchTxt string, testCases TestCasesMap) {
+	switch format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		response := SearchResponse{Pattern: searchTxt, TestCases: TcJsonRecords(testCases, s.WorkItems)}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			errorTxt := fmt.Sprintf("ERROR: Couldn't write response: %v", err)
+			log.Print(ErrorStyle.Render(errorTxt))
+		}
+	case "xml":
+		protocols := CreateProtocolXml(testCases, s.WorkItems)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("Content-Disposition", `attachment; filename="search_tc.xml"`)
+		fmt.Fprint(w, XmlText(s.Template, searchTxt, protocols))
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %s (expected json or xml)", format), http.StatusBadRequest)
+	}
+}
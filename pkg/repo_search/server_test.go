@@ -0,0 +1,215 @@
+package repo_search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+const serverTcTemplate = `"""
+Polarion ID: %s
+Setup: Bench A
+Initial estimate: 00:01:00
+"""
+`
+
+func newTestServer(t *testing.T, fsys fstest.MapFS) (*httptest.Server, *Server) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	repo := NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py"}}, nil)
+	repo.Output = io.Discard
+	s := &Server{Repo: repo, Template: VlReplaceResults, Degrees: 3}
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return server, s
+}
+
+func serverTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"lib/heater.py": {Data: []byte("class Heater:\n    def __init__(self):\n        self.on = False\n\n" +
+			"    def power(self, v):\n        HEATER_IMPL(v)\n")},
+		"test_cases/heater/test_001_power.py": {Data: []byte(strings.Replace(serverTcTemplate, "%s", "4AP2-1001", 1) +
+			"from lib.heater import Heater\n\ndef test_001_power():\n    Heater().power(1)\n")},
+		"test_cases/heater/test_002_off.py": {Data: []byte(strings.Replace(serverTcTemplate, "%s", "4AP2-1002", 1) +
+			"def test_002_off(heater):\n    heater.off()\n")},
+	}
+}
+
+// tcIds Requests path with query and returns the IDs of the found TCs
+func tcIds(server *httptest.Server, path string, query url.Values) ([]string, error) {
+	resp, err := http.Get(server.URL + path + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s?%s: status %d: %s", path, query.Encode(), resp.StatusCode, body)
+	}
+
+	var response SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, tc := range response.TestCases {
+		ids = append(ids, tc.Id)
+	}
+	return ids, nil
+}
+
+func getTcIds(t *testing.T, server *httptest.Server, path string, query url.Values) []string {
+	t.Helper()
+	ids, err := tcIds(server, path, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestServerSearch(t *testing.T) {
+	server, _ := newTestServer(t, serverTestFS())
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{"literal", url.Values{"pattern": {"HEATER_IMPL"}}, []string{"4AP2-1001"}},
+		{"regex", url.Values{"pattern": {`heater\.o(n|ff)`}, "match": {"regex"}}, []string{"4AP2-1002"}},
+		{"no recursion", url.Values{"pattern": {"HEATER_IMPL"}, "dist": {"1"}}, []string{}},
+		{"not found", url.Values{"pattern": {"COOLER_IMPL"}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getTcIds(t, server, "/search", tt.query); !equalStrings(got, tt.want) {
+				t.Errorf("found TCs %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerSymbol(t *testing.T) {
+	server, _ := newTestServer(t, serverTestFS())
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{"method", url.Values{"file": {"lib/heater.py"}, "class": {"Heater"}, "name": {"power"}}, []string{"4AP2-1001"}},
+		{"constructor", url.Values{"file": {"lib/heater.py"}, "class": {"Heater"}, "name": {"__init__"}}, []string{"4AP2-1001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getTcIds(t, server, "/symbol", tt.query); !equalStrings(got, tt.want) {
+				t.Errorf("found TCs %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	server, _ := newTestServer(t, serverTestFS())
+
+	tests := []struct {
+		name   string
+		path   string
+		query  url.Values
+		status int
+	}{
+		{"missing pattern", "/search", url.Values{}, http.StatusBadRequest},
+		{"unknown match", "/search", url.Values{"pattern": {"x"}, "match": {"fuzzy"}}, http.StatusBadRequest},
+		{"invalid dist", "/search", url.Values{"pattern": {"x"}, "dist": {"far"}}, http.StatusBadRequest},
+		{"unknown format", "/search", url.Values{"pattern": {"x"}, "format": {"yaml"}}, http.StatusBadRequest},
+		{"missing name", "/symbol", url.Values{"file": {"lib/heater.py"}}, http.StatusBadRequest},
+		{"unknown file", "/symbol", url.Values{"file": {"lib/cooler.py"}, "name": {"power"}}, http.StatusNotFound},
+		{"constructor without class", "/symbol", url.Values{"file": {"lib/heater.py"}, "name": {"__init__"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path + "?" + tt.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestServerXml(t *testing.T) {
+	server, _ := newTestServer(t, serverTestFS())
+
+	resp, err := http.Get(server.URL + "/search?pattern=HEATER_IMPL&format=xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `id="4AP2-1001"`) {
+		t.Errorf("xml doesn't contain the protocol of 4AP2-1001:\n%s", body)
+	}
+}
+
+// TestServerRefreshDuringSearch Run with -race: searches and refreshes of
+// changed files must not access the repo at the same time
+func TestServerRefreshDuringSearch(t *testing.T) {
+	fsys := serverTestFS()
+	server, s := newTestServer(t, fsys)
+
+	texts := []string{
+		"def test_003_new():\n    HEATER_IMPL(3)\n",
+		"def test_003_new():\n    pass\n",
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			// The fs is only changed while no search reads it
+			s.mu.Lock()
+			fsys["test_cases/heater/test_003_new.py"] = &fstest.MapFile{
+				Data: []byte(strings.Replace(serverTcTemplate, "%s", "4AP2-1003", 1) + texts[i%2]),
+			}
+			s.mu.Unlock()
+			s.Refresh()
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				ids, err := tcIds(server, "/search", url.Values{"pattern": {"HEATER_IMPL"}})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(ids) == 0 || ids[0] != "4AP2-1001" {
+					t.Errorf("found TCs %q, want 4AP2-1001 (and 4AP2-1003)", ids)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Last written text doesn't use HEATER_IMPL
+	if got := getTcIds(t, server, "/search", url.Values{"pattern": {"HEATER_IMPL"}}); !equalStrings(got, []string{"4AP2-1001"}) {
+		t.Errorf("found TCs %q after the last refresh, want 4AP2-1001", got)
+	}
+}
@@ -0,0 +1,151 @@
+package repo_search
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Root Searched directory and the file system its files are read from. Files of
+// the root are identified by Dir joined with their path inside of FS.
+type Root struct {
+	Dir string
+	FS  fs.FS
+}
+
+// Close Releases the resources of the file system (i.e. open archives or
+// git processes) if it has any
+func (r Root) Close() error {
+	if closer, ok := r.FS.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// IsSnapshot Reports whether the root's files are read from an archive or a git
+// revision, which never change
+func (r Root) IsSnapshot() bool {
+	switch r.FS.(type) {
+	case *zip.ReadCloser, *archiveFS, *GitFS:
+		return true
+	}
+	return false
+}
+
+func DirRoot(dir string) Root {
+	return Root{Dir: dir, FS: os.DirFS(dir)}
+}
+
+// OpenRoot Opens a directory or a zip/tar snapshot of it. Archives are read
+// without unpacking them. Their files never change (and have no modification
+// time) -> Refresh doesn't see changes of the archive itself.
+func OpenRoot(path string) (Root, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Root{}, err
+	}
+	if info.IsDir() {
+		return DirRoot(path), nil
+	}
+
+	lowerPath := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lowerPath, ".zip"):
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return Root{}, err
+		}
+		return Root{Dir: path, FS: reader}, nil
+	case strings.HasSuffix(lowerPath, ".tar"),
+		strings.HasSuffix(lowerPath, ".tar.gz"),
+		strings.HasSuffix(lowerPath, ".tgz"):
+		fsys, err := readTar(path)
+		if err != nil {
+			return Root{}, err
+		}
+		return Root{Dir: path, FS: fsys}, nil
+	}
+	return Root{}, fmt.Errorf("%s is neither a directory nor a zip/tar archive", path)
+}
+
+// readTar Loads the regular files of a (gzipped) tar archive into memory
+func readTar(tarPath string) (*archiveFS, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if !strings.HasSuffix(strings.ToLower(tarPath), ".tar") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	fsys := newArchiveFS()
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		// Names that would leave the archive's root (../ or absolute) are invalid
+		name := path.Clean(filepath.ToSlash(header.Name))
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid file name %q in %s", header.Name, tarPath)
+		}
+		fsys.add(name, data)
+	}
+	sortDirs(fsys.dirs)
+	return fsys, nil
+}
+
+// ReadFileList Reads one path per line (i.e. the output of `git ls-files`)
+func ReadFileList(r io.Reader) ([]string, error) {
+	files := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// RootFile Returns the path of file inside of the file system of the root
+func (r Root) RootFile(file string) (string, bool) {
+	rel, err := filepath.Rel(r.Dir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// File Returns the path that identifies the file at rel inside of the root
+func (r Root) File(rel string) string {
+	return filepath.Join(r.Dir, filepath.FromSlash(rel))
+}
@@ -0,0 +1,390 @@
+package repo_search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type pyTokenKind int
+
+const (
+	pyName pyTokenKind = iota
+	pyNumber
+	pyString
+	pyOp
+	// pyNewline End of a logical line (line breaks inside of brackets are ignored)
+	pyNewline
+	// pyPlaceholder `$name` in a pattern, matches one expression (i.e. `self.heaters[0]`)
+	pyPlaceholder
+	// pyEllipsis `$...` in a pattern, matches any (possibly empty) balanced token sequence
+	pyEllipsis
+)
+
+type pyToken struct {
+	kind  pyTokenKind
+	text  string
+	start int
+	end   int
+}
+
+var pythonKeywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true, "break": true,
+	"class": true, "continue": true, "def": true, "del": true, "elif": true, "else": true,
+	"except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true,
+	"or": true, "pass": true, "raise": true, "return": true, "try": true, "while": true,
+	"with": true, "yield": true,
+}
+
+// Longest operators first so that i.e. `**=` isn't split into `**` and `=`
+var pythonOperators = []string{
+	"**=", "//=", ">>=", "<<=", "...",
+	"**", "//", "==", "!=", "<=", ">=", "->", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=", ":=", "<<", ">>",
+}
+
+// Structural Matches a python code pattern token by token, ignoring whitespace,
+// line breaks inside of brackets and comments. `$name` matches any expression
+// (the same text wherever the same name is used) and `$...` any sequence of
+// tokens, i.e. `$obj.outputHeater.set_disconnected($...)`.
+type Structural struct {
+	Pattern string
+	tokens  []pyToken
+}
+
+func NewStructural(pattern string) (Structural, error) {
+	tokens, err := tokenizePython(pattern, true)
+	if err != nil {
+		return Structural{}, err
+	}
+
+	// Line breaks around the pattern are irrelevant
+	for len(tokens) > 0 && tokens[0].kind == pyNewline {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].kind == pyNewline {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return Structural{}, fmt.Errorf("empty structural pattern")
+	}
+	if _, err := matchingBrackets(tokens); err != nil {
+		return Structural{}, err
+	}
+
+	return Structural{Pattern: pattern, tokens: tokens}, nil
+}
+
+func (s Structural) String() string {
+	return s.Pattern
+}
+
+// FindAll Only python files are tokenized, other files never match
+func (s Structural) FindAll(text string, lang Language, _ string) [][]int {
+	if _, ok := lang.(Python); !ok {
+		return nil
+	}
+
+	tokens, err := tokenizePython(text, false)
+	if err != nil {
+		return nil
+	}
+	// Unbalanced brackets only break matches that contain them
+	closes, _ := matchingBrackets(tokens)
+
+	results := [][]int{}
+	for t := 0; t < len(tokens); {
+		end, ok := s.matchAt(tokens, closes, 0, t, map[string]string{})
+		if !ok || end == t {
+			t++
+			continue
+		}
+		results = append(results, []int{tokens[t].start, tokens[end-1].end})
+		t = end
+	}
+	return results
+}
+
+// matchAt Matches pattern tokens from p on against text tokens from t on and
+// returns the index after the last matched text token
+func (s Structural) matchAt(tokens []pyToken, closes []int, p, t int, bindings map[string]string) (int, bool) {
+	if p == len(s.tokens) {
+		return t, true
+	}
+
+	patternToken := s.tokens[p]
+	switch patternToken.kind {
+	case pyEllipsis:
+		// Shortest sequence first
+		for _, end := range balancedEnds(tokens, closes, t) {
+			if matchEnd, ok := s.matchAt(tokens, closes, p+1, end, bindings); ok {
+				return matchEnd, true
+			}
+		}
+	case pyPlaceholder:
+		// Longest expression first
+		ends := expressionEnds(tokens, closes, t)
+		for i := len(ends) - 1; i >= 0; i-- {
+			value := joinTokens(tokens[t:ends[i]])
+			if bound, ok := bindings[patternToken.text]; ok && bound != value {
+				continue
+			}
+
+			newBindings := map[string]string{patternToken.text: value}
+			for name, bound := range bindings {
+				newBindings[name] = bound
+			}
+			if matchEnd, ok := s.matchAt(tokens, closes, p+1, ends[i], newBindings); ok {
+				return matchEnd, true
+			}
+		}
+	default:
+		if t < len(tokens) && tokens[t].kind == patternToken.kind && tokens[t].text == patternToken.text {
+			return s.matchAt(tokens, closes, p+1, t+1, bindings)
+		}
+	}
+	return 0, false
+}
+
+// balancedEnds Returns the possible ends of balanced token sequences that start
+// at t. They stop before a closing bracket of an enclosing group or a newline.
+func balancedEnds(tokens []pyToken, closes []int, t int) []int {
+	ends := []int{t}
+	for i := t; i < len(tokens); {
+		token := tokens[i]
+		if token.kind == pyNewline || isClosingBracket(token) {
+			break
+		}
+		if isOpeningBracket(token) {
+			if closes[i] == -1 {
+				break
+			}
+			i = closes[i]
+		}
+		i++
+		ends = append(ends, i)
+	}
+	return ends
+}
+
+// expressionEnds Returns the possible ends of a primary expression (an atom
+// followed by attributes, calls and subscripts) that starts at t
+func expressionEnds(tokens []pyToken, closes []int, t int) []int {
+	if t >= len(tokens) {
+		return nil
+	}
+
+	end := t
+	token := tokens[t]
+	switch {
+	case token.kind == pyName && !pythonKeywords[token.text], token.kind == pyNumber:
+		end = t + 1
+	case token.kind == pyString:
+		// Adjacent strings are concatenated
+		for end < len(tokens) && tokens[end].kind == pyString {
+			end++
+		}
+	case isOpeningBracket(token) && closes[t] != -1:
+		end = closes[t] + 1
+	default:
+		return nil
+	}
+
+	ends := []int{end}
+	for end < len(tokens) {
+		next := tokens[end]
+		switch {
+		case next.text == "." && next.kind == pyOp && end+1 < len(tokens) && tokens[end+1].kind == pyName:
+			end += 2
+		case (next.text == "(" || next.text == "[") && closes[end] != -1:
+			end = closes[end] + 1
+		default:
+			return ends
+		}
+		ends = append(ends, end)
+	}
+	return ends
+}
+
+// matchingBrackets Returns for every opening bracket the index of its closing
+// one (-1 for every other token)
+func matchingBrackets(tokens []pyToken) ([]int, error) {
+	closes := make([]int, len(tokens))
+	stack := []int{}
+	var err error
+	for i, token := range tokens {
+		closes[i] = -1
+		switch {
+		case isOpeningBracket(token):
+			stack = append(stack, i)
+		case isClosingBracket(token):
+			if len(stack) == 0 || !bracketsMatch(tokens[stack[len(stack)-1]].text, token.text) {
+				err = fmt.Errorf("unbalanced %q at offset %d", token.text, token.start)
+				stack = nil
+				continue
+			}
+			closes[stack[len(stack)-1]] = i
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 && err == nil {
+		err = fmt.Errorf("unclosed %q at offset %d", tokens[stack[0]].text, tokens[stack[0]].start)
+	}
+	return closes, err
+}
+
+func isOpeningBracket(token pyToken) bool {
+	return token.kind == pyOp && (token.text == "(" || token.text == "[" || token.text == "{")
+}
+
+func isClosingBracket(token pyToken) bool {
+	return token.kind == pyOp && (token.text == ")" || token.text == "]" || token.text == "}")
+}
+
+func bracketsMatch(open, close string) bool {
+	return open+close == "()" || open+close == "[]" || open+close == "{}"
+}
+
+func joinTokens(tokens []pyToken) string {
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.text
+	}
+	return strings.Join(texts, " ")
+}
+
+// tokenizePython Splits python code into tokens. Comments and whitespace are
+// dropped. placeholders enables `$name` and `$...` (for patterns).
+func tokenizePython(text string, placeholders bool) ([]pyToken, error) {
+	tokens := []pyToken{}
+	depth := 0
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\f' || c == '\r':
+			i++
+		case c == '\\' && strings.HasPrefix(text[i+1:], "\n"):
+			// Explicit line continuation
+			i += 2
+		case c == '\\' && strings.HasPrefix(text[i+1:], "\r\n"):
+			i += 3
+		case c == '#':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '\n':
+			if depth == 0 && len(tokens) > 0 && tokens[len(tokens)-1].kind != pyNewline {
+				tokens = append(tokens, pyToken{kind: pyNewline, text: "\n", start: i, end: i + 1})
+			}
+			i++
+		case placeholders && c == '$':
+			if strings.HasPrefix(text[i:], "$...") {
+				tokens = append(tokens, pyToken{kind: pyEllipsis, text: "$...", start: i, end: i + 4})
+				i += 4
+				continue
+			}
+			end := identifierEnd(text, i+1)
+			if end == i+1 {
+				return nil, fmt.Errorf("expected placeholder name after $ at offset %d", i)
+			}
+			tokens = append(tokens, pyToken{kind: pyPlaceholder, text: text[i:end], start: i, end: end})
+			i = end
+		case stringStart(text, i) != -1:
+			end, err := stringEnd(text, stringStart(text, i))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, pyToken{kind: pyString, text: text[i:end], start: i, end: end})
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(text) && isDigit(text[i+1])):
+			end := i
+			for end < len(text) && (isIdentifierByte(text[end]) || text[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, pyToken{kind: pyNumber, text: text[i:end], start: i, end: end})
+			i = end
+		case identifierEnd(text, i) > i:
+			end := identifierEnd(text, i)
+			tokens = append(tokens, pyToken{kind: pyName, text: text[i:end], start: i, end: end})
+			i = end
+		default:
+			op := string(c)
+			for _, candidate := range pythonOperators {
+				if strings.HasPrefix(text[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			switch op {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+			tokens = append(tokens, pyToken{kind: pyOp, text: op, start: i, end: i + len(op)})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// identifierEnd Returns the end of the identifier that starts at i (i if there is none)
+func identifierEnd(text string, i int) int {
+	end := i
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		isStart := r == '_' || unicode.IsLetter(r)
+		if !isStart && (end == i || !unicode.IsDigit(r)) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+// stringStart Returns the index of the opening quote if a string literal
+// (with an optional prefix like f or rb) starts at i, otherwise -1
+func stringStart(text string, i int) int {
+	for j := i; j < len(text) && j <= i+2; j++ {
+		switch text[j] {
+		case '\'', '"':
+			return j
+		case 'r', 'R', 'b', 'B', 'u', 'U', 'f', 'F':
+			continue
+		}
+		return -1
+	}
+	return -1
+}
+
+// stringEnd Returns the index after the string literal that opens at quoteIdx
+func stringEnd(text string, quoteIdx int) (int, error) {
+	quote := text[quoteIdx : quoteIdx+1]
+	if strings.HasPrefix(text[quoteIdx:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+
+	for i := quoteIdx + len(quote); i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == '\n' && len(quote) == 1:
+			return 0, fmt.Errorf("unterminated string at offset %d", quoteIdx)
+		case strings.HasPrefix(text[i:], quote):
+			return i + len(quote), nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", quoteIdx)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,128 @@
+package repo_search
+
+import "testing"
+
+func TestTokenizePython(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		placeholders bool
+		want         []string
+	}{
+		{"call", "heater.power(10)", false, []string{"heater", ".", "power", "(", "10", ")"}},
+		{"comments and whitespace", "x  =  1  # set x\n", false, []string{"x", "=", "1", "\n"}},
+		{"line break in brackets", "power(\n    1,\n    2)\n", false, []string{"power", "(", "1", ",", "2", ")", "\n"}},
+		{"line continuation", "x = 1 + \\\n    2", false, []string{"x", "=", "1", "+", "2"}},
+		{"crlf", "x = 1\r\ny = 2\r\n", false, []string{"x", "=", "1", "\n", "y", "=", "2", "\n"}},
+		{"longest operator", "x **= 2", false, []string{"x", "**=", "2"}},
+		{"strings", `f"a # b" + 'c\'d' + """e"""`, false, []string{`f"a # b"`, "+", `'c\'d'`, "+", `"""e"""`}},
+		{"placeholders", "$obj.set($...)", true, []string{"$obj", ".", "set", "(", "$...", ")"}},
+		{"dollar without placeholders", "$obj", false, []string{"$", "obj"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenizePython(tt.text, tt.placeholders)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, token := range tokens {
+				got = append(got, token.text)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("tokens = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizePythonErrors(t *testing.T) {
+	for _, text := range []string{`x = "abc`, "x = '''abc", "$ + 1"} {
+		if _, err := tokenizePython(text, true); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+func TestNewStructuralErrors(t *testing.T) {
+	for _, pattern := range []string{"", "\n\n", "power(", "power)", "$"} {
+		if _, err := NewStructural(pattern); err == nil {
+			t.Errorf("expected error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestStructuralFindAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		want    []string
+	}{
+		{
+			name:    "ignores whitespace and line breaks",
+			pattern: "heater.power(10)",
+			text:    "heater.power(\n    10\n)\nheater.power(11)\n",
+			want:    []string{"heater.power(\n    10\n)"},
+		},
+		{
+			name:    "ignores comments",
+			pattern: "power(1, 2)",
+			text:    "power(1,  # first\n      2)\n# power(1, 2)\n",
+			want:    []string{"power(1,  # first\n      2)"},
+		},
+		{
+			name:    "placeholder matches an expression",
+			pattern: "$obj.set_disconnected()",
+			text:    "self.heaters[0].set_disconnected()\nheater.set_disconnected()\n",
+			want:    []string{"self.heaters[0].set_disconnected()", "heater.set_disconnected()"},
+		},
+		{
+			name:    "same placeholder must match the same text",
+			pattern: "$a = $a",
+			text:    "x = x\nx = y\n",
+			want:    []string{"x = x"},
+		},
+		{
+			name:    "ellipsis matches any arguments",
+			pattern: "power($...)",
+			text:    "power()\npower(1, f(2, 3))\n",
+			want:    []string{"power()", "power(1, f(2, 3))"},
+		},
+		{
+			name:    "no match in strings",
+			pattern: "power(1)",
+			text:    "x = \"power(1)\"\n",
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			structural, err := NewStructural(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, match := range structural.FindAll(tt.text, Python{}, "") {
+				got = append(got, tt.text[match[0]:match[1]])
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("matches = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructuralFindAllOtherLanguages(t *testing.T) {
+	structural, err := NewStructural("power(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches := structural.FindAll("power(1)", Cpp{}, ""); len(matches) != 0 {
+		t.Errorf("expected no matches outside of python, got %v", matches)
+	}
+}
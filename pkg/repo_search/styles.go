@@ -28,4 +28,8 @@ var (
 	ErrorStyle = lipgloss.NewStyle().
 			Bold(true).
 			Foreground(lipgloss.Color("9")) // bright red
+
+	CursorStyle = lipgloss.NewStyle().
+			Bold(true).
+			Reverse(true)
 )
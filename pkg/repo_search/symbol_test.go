@@ -0,0 +1,163 @@
+package repo_search
+
+import (
+	"io"
+	"log"
+	"os"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+// newTestRepo Repo of python files (path -> text) in root "repo"
+func newTestRepo(t *testing.T, files map[string]string) *Repo {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	fsys := fstest.MapFS{}
+	for path, text := range files {
+		fsys[path] = &fstest.MapFile{Data: []byte(text)}
+	}
+	return NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py", ".robot", ".resource", ".c", ".h"}}, nil)
+}
+
+// searchedFiles Returns the files (relative to the root) that symbol is searched in
+func searchedFiles(repo *Repo, symbol Symbol) []string {
+	files := []string{}
+	for _, file := range repo.files {
+		if len(symbol.NamesIn(file)) > 0 {
+			files = append(files, repo.RelPath(file))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestSymbolSubclasses(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/hal.py": "class Heater:\n    def power(self, v):\n        HEATER_IMPL(v)\n",
+		"lib/sim.py": "from lib.hal import Heater\n\nclass SimHeater(Heater):\n    pass\n",
+		"lib/fake.py": "from lib.hal import Heater\n\nclass FakeHeater(Heater):\n" +
+			"    def power(self, v):\n        pass\n",
+		"lib/wrap.py": "from lib.hal import Heater\n\nclass WrapHeater(Heater):\n" +
+			"    def power(self, v):\n        super().power(v)\n",
+		"lib/use_sim.py":   "from lib.sim import SimHeater\n\nSimHeater().power(1)\n",
+		"lib/use_fake.py":  "from lib.fake import FakeHeater\n\nFakeHeater().power(1)\n",
+		"lib/use_wrap.py":  "from lib.wrap import WrapHeater\n\nWrapHeater().power(1)\n",
+		"lib/other.py":     "class Pump:\n    def power(self, v):\n        pass\n",
+		"lib/use_other.py": "from lib.other import Pump\n\nPump().power(1)\n",
+	})
+
+	symbol := repo.Symbol(Scope{Name: "power", Class: "Heater"}, "repo/lib/hal.py")
+
+	want := []string{"lib/fake.py", "lib/hal.py", "lib/sim.py", "lib/use_sim.py", "lib/use_wrap.py", "lib/wrap.py"}
+	if got := searchedFiles(repo, symbol); !equalStrings(got, want) {
+		t.Errorf("searched files = %q, want %q", got, want)
+	}
+
+	// Overrides are expected declarations, even if they shadow the method
+	for _, file := range []string{"repo/lib/hal.py", "repo/lib/fake.py", "repo/lib/wrap.py"} {
+		if !symbol.hierarchy[file] {
+			t.Errorf("expected %s in the class hierarchy", file)
+		}
+	}
+	if symbol.hierarchy["repo/lib/other.py"] {
+		t.Error("unrelated class with the same method name is in the hierarchy")
+	}
+}
+
+func TestSymbolImporters(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{
+		"lib/hal/heater.py": "class Heater:\n    def connect(self):\n        HEATER_IMPL()\n\n" +
+			"def reset():\n    pass\n",
+		"lib/hal/pump.py":      "class Pump:\n    def connect(self):\n        pass\n\ndef reset():\n    pass\n",
+		"lib/use_heater.py":    "from lib.hal.heater import Heater\n\nHeater().connect()\n",
+		"lib/use_pump.py":      "from lib.hal.pump import Pump, reset\n\nPump().connect()\nreset()\n",
+		"lib/use_module.py":    "from lib.hal import heater\n\nheater.reset()\n",
+		"lib/use_alias.py":     "from lib.hal.heater import reset as heater_reset\n\nheater_reset()\n",
+		"lib/use_unrelated.py": "def connect():\n    pass\n",
+	})
+
+	tests := []struct {
+		name  string
+		scope Scope
+		want  map[string][]string
+	}{
+		{
+			name:  "method",
+			scope: Scope{Name: "connect", Class: "Heater"},
+			want: map[string][]string{
+				"lib/hal/heater.py": {"connect"},
+				"lib/use_heater.py": {"connect"},
+				"lib/use_module.py": {"connect"},
+				// Imports something from the module, which might return a Heater
+				"lib/use_alias.py": {"connect"},
+			},
+		},
+		{
+			name:  "function",
+			scope: Scope{Name: "reset"},
+			want: map[string][]string{
+				"lib/hal/heater.py": {"reset"},
+				"lib/use_heater.py": {"reset"},
+				"lib/use_module.py": {"reset"},
+				"lib/use_alias.py":  {"heater_reset"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol := repo.Symbol(tt.scope, "repo/lib/hal/heater.py")
+			for _, file := range repo.files {
+				rel := repo.RelPath(file)
+				if got := symbol.NamesIn(file); !equalStrings(got, tt.want[rel]) {
+					t.Errorf("names in %s = %q, want %q", rel, got, tt.want[rel])
+				}
+			}
+		})
+	}
+}
+
+func TestSymbolUnresolvedImport(t *testing.T) {
+	// Searched root is not on the python path as the imports expect
+	repo := newTestRepo(t, map[string]string{
+		"hal/heater.py":    "class Heater:\n    def connect(self):\n        HEATER_IMPL()\n\ndef reset():\n    pass\n",
+		"tests/test_a.py":  "from bench.hal.heater import Heater\n\nHeater().connect()\n",
+		"tests/test_b.py":  "from bench.hal.heater import reset\n\nreset()\n",
+		"tests/test_c.py":  "import bench.hal.heater\n\nbench.hal.heater.reset()\n",
+		"tests/unused.py":  "x = 1\n",
+		"tests/helpers.py": "from bench.other import thing\n",
+	})
+
+	tests := []struct {
+		name  string
+		scope Scope
+	}{
+		{"method", Scope{Name: "connect", Class: "Heater"}},
+		{"function", Scope{Name: "reset"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol := repo.Symbol(tt.scope, "repo/hal/heater.py")
+			if symbol.names != nil {
+				t.Fatalf("expected a search in all files, got names %v", symbol.names)
+			}
+			if got := symbol.NamesIn("repo/tests/unused.py"); !equalStrings(got, []string{tt.scope.Name}) {
+				t.Errorf("names = %q, want %q", got, tt.scope.Name)
+			}
+		})
+	}
+
+	// Resolvable imports keep the search scoped to the importers
+	repo = newTestRepo(t, map[string]string{
+		"hal/heater.py":   "def reset():\n    pass\n",
+		"tests/test_a.py": "from hal.heater import reset\n\nreset()\n",
+		"tests/unused.py": "x = 1\n",
+	})
+	symbol := repo.Symbol(Scope{Name: "reset"}, "repo/hal/heater.py")
+	if symbol.names == nil || len(symbol.NamesIn("repo/tests/unused.py")) != 0 {
+		t.Errorf("expected the search to be scoped to importers, got %v", symbol.names)
+	}
+}
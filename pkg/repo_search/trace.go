@@ -0,0 +1,121 @@
+package repo_search
+
+import (
+	"strings"
+	"sync"
+)
+
+// SearchTrace What a (recursive) search depended on: the terms it searched for
+// and the files it found something in. Changes of other files that don't
+// contain any of the terms can't change the found TCs (i.e. in watch mode
+// there is no need to search again).
+type SearchTrace struct {
+	mu sync.Mutex
+	// terms Symbols are only searched by name cause their imports can change
+	terms []Matcher
+	// files Files with matches, importers of searched modules and found TC files
+	files map[string]bool
+	// scopes Every TC in them is reached (scopes of autouse fixtures)
+	scopes []string
+}
+
+func (t *SearchTrace) addTerm(term Matcher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	symbol, ok := term.(Symbol)
+	if !ok {
+		t.terms = append(t.terms, term)
+		return
+	}
+	// Without names the symbol is searched by its name in every file
+	t.terms = append(t.terms, Symbol{Name: symbol.Name, Keyword: symbol.Keyword})
+	if symbol.Class != "" {
+		// Changes of the class hierarchy change where methods are searched
+		t.terms = append(t.terms, Identifier(symbol.Class))
+	}
+}
+
+func (t *SearchTrace) addFiles(files ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.files == nil {
+		t.files = map[string]bool{}
+	}
+	for _, file := range files {
+		t.files[file] = true
+	}
+}
+
+func (t *SearchTrace) addScopes(scopes ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scopes = append(t.scopes, scopes...)
+}
+
+// Affected Returns the changed files (see Repo.Refresh) that can change the
+// TCs found by the traced search
+func (t *SearchTrace) Affected(repo *Repo, changed []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	affected := []string{}
+	for _, file := range changed {
+		lang := LanguageForFile(file)
+		if t.files[file] {
+			affected = append(affected, file)
+			continue
+		}
+		if _, ok := InFixtureScopes(file, t.scopes); ok && lang.IsTcFile(file) {
+			affected = append(affected, file)
+			continue
+		}
+
+		// Removed files that the search didn't depend on
+		if _, ok := repo.FindFile(file); !ok {
+			continue
+		}
+		text, err := repo.ReadText(file)
+		if err != nil {
+			// Can't tell -> better search again
+			affected = append(affected, file)
+			continue
+		}
+		for _, term := range t.terms {
+			if len(term.FindAll(text, lang, file)) > 0 {
+				affected = append(affected, file)
+				break
+			}
+		}
+	}
+	return affected
+}
+
+func (r *Repo) traceTerm(term Matcher) {
+	if r.Trace != nil {
+		r.Trace.addTerm(term)
+	}
+}
+
+func (r *Repo) traceFiles(files ...string) {
+	if r.Trace != nil {
+		r.Trace.addFiles(files...)
+	}
+}
+
+func (r *Repo) traceScopes(scopes ...string) {
+	if r.Trace != nil {
+		r.Trace.addScopes(scopes...)
+	}
+}
+
+// traceModule Changes of the importers of the module file are found by the
+// module's name (its last part is in every import of the module)
+func (r *Repo) traceModule(file string) {
+	module := r.imports.Module(file)
+	if module == "" {
+		return
+	}
+	r.traceTerm(Identifier(module[strings.LastIndex(module, ".")+1:]))
+}
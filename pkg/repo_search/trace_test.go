@@ -0,0 +1,125 @@
+package repo_search
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTraceAffected(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(fsys fstest.MapFS)
+		affected []string
+	}{
+		{
+			name: "unrelated change",
+			change: func(fsys fstest.MapFS) {
+				fsys["lib/cooler.py"] = &fstest.MapFile{Data: []byte("def cool():\n    stop()\n")}
+			},
+			affected: []string{},
+		},
+		{
+			name: "removed unrelated file",
+			change: func(fsys fstest.MapFS) {
+				delete(fsys, "lib/cooler.py")
+			},
+			affected: []string{},
+		},
+		{
+			name: "added usage",
+			change: func(fsys fstest.MapFS) {
+				fsys["lib/helper.py"] = &fstest.MapFile{Data: []byte("from lib.heater import heat\n\ndef warm():\n    heat(1)\n")}
+			},
+			affected: []string{"repo/lib/helper.py"},
+		},
+		{
+			name: "TC starts using the function",
+			change: func(fsys fstest.MapFS) {
+				fsys["test_cases/cooler/test_002_cool.py"] = &fstest.MapFile{
+					Data: []byte(tcText("4AP2-1002", "from lib.heater import heat\n\ndef test_002_cool():\n    heat(0)\n")),
+				}
+			},
+			affected: []string{"repo/test_cases/cooler/test_002_cool.py"},
+		},
+		{
+			name: "modified traced file",
+			change: func(fsys fstest.MapFS) {
+				fsys["lib/heater.py"] = &fstest.MapFile{Data: []byte("def heat(v):\n    pass\n")}
+			},
+			affected: []string{"repo/lib/heater.py"},
+		},
+		{
+			name: "removed traced file",
+			change: func(fsys fstest.MapFS) {
+				delete(fsys, "test_cases/heater/test_001_power.py")
+			},
+			affected: []string{"repo/test_cases/heater/test_001_power.py"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"lib/heater.py":   {Data: []byte("def heat(v):\n    HEATER_IMPL.apply(v)\n")},
+				"lib/cooler.py":   {Data: []byte("def cool():\n    pass\n")},
+				"lib/__init__.py": {Data: []byte("")},
+				"test_cases/heater/test_001_power.py": {
+					Data: []byte(tcText("4AP2-1001", "from lib.heater import heat\n\ndef test_001_power():\n    heat(5)\n")),
+				},
+				"test_cases/cooler/test_002_cool.py": {
+					Data: []byte(tcText("4AP2-1002", "from lib.cooler import cool\n\ndef test_002_cool():\n    cool()\n")),
+				},
+			}
+			repo := NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py"}}, nil)
+			repo.Output = io.Discard
+			repo.Trace = &SearchTrace{}
+
+			testCases := SearchForUsagesInTc(repo, SearchedSet{}, Literal("HEATER_IMPL"), 3)
+			if len(testCases) != 1 {
+				t.Fatalf("found %d TCs, want 1", len(testCases))
+			}
+
+			test.change(fsys)
+			affected := repo.Trace.Affected(repo, repo.Refresh())
+			if !equalStrings(affected, test.affected) {
+				t.Errorf("affected %q, want %q", affected, test.affected)
+			}
+		})
+	}
+}
+
+func TestRefreshDetectsChanges(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/heater.py": {Data: []byte("def heat(v):\n    pass\n")},
+		"lib/cooler.py": {Data: []byte("def cool():\n    pass\n")},
+		"lib/sensor.py": {Data: []byte("def read():\n    pass\n")},
+	}
+	repo := NewRepo([]Root{{Dir: "repo", FS: fsys}}, FileFilter{FileTypes: []string{".py"}}, nil)
+
+	if changed := repo.Refresh(); len(changed) != 0 {
+		t.Fatalf("changed %q without changes", changed)
+	}
+
+	fsys["lib/valve.py"] = &fstest.MapFile{Data: []byte("def open():\n    pass\n")}
+	delete(fsys, "lib/cooler.py")
+	fsys["lib/heater.py"] = &fstest.MapFile{Data: []byte("def heat(v):\n    apply(v)\n")}
+	fsys["lib/sensor.py"] = &fstest.MapFile{Data: []byte("def read():\n    pass\n"), ModTime: time.Unix(1, 0)}
+
+	want := []string{"repo/lib/cooler.py", "repo/lib/heater.py", "repo/lib/sensor.py", "repo/lib/valve.py"}
+	if changed := repo.Refresh(); !equalStrings(changed, want) {
+		t.Errorf("changed %q, want %q", changed, want)
+	}
+	if _, ok := repo.FindFile("repo/lib/cooler.py"); ok {
+		t.Error("expected the removed file to be gone")
+	}
+	if text, err := repo.ReadText("repo/lib/heater.py"); err != nil || text != "def heat(v):\n    apply(v)\n" {
+		t.Errorf("read %q (%v), want the modified text", text, err)
+	}
+}
+
+// tcText Python TC file with the docstring of TC id
+func tcText(id, body string) string {
+	return "\"\"\"\nPolarion ID: " + id + "\nSetup: Bench A\n\"\"\"\n" + body
+}
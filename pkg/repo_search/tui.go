@@ -0,0 +1,213 @@
+package repo_search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const reviewHelpTxt = "↑/↓ move • space select • enter expand • a all • n none • w write • q quit"
+
+type reviewRow struct {
+	setup string
+	// tc is nil for setup header rows
+	tc *TestCase
+}
+
+type reviewModel struct {
+	rows       []reviewRow
+	tcsBySetup map[string][]*TestCase // setup -> tcs of that setup
+	selected   map[string]bool        // TC ID -> is selected
+	expanded   map[string]bool        // TC ID -> is expanded
+	cursor     int
+	height     int
+
+	confirmed bool
+}
+
+func newReviewModel(testCases TestCasesMap) reviewModel {
+	m := reviewModel{
+		tcsBySetup: map[string][]*TestCase{},
+		selected:   map[string]bool{},
+		expanded:   map[string]bool{},
+	}
+
+	tcBySetup := GetTcBySetup(testCases)
+	setups := make([]string, 0, len(tcBySetup))
+	for setup := range tcBySetup {
+		setups = append(setups, setup)
+	}
+	sort.Strings(setups)
+
+	for _, setup := range setups {
+		m.rows = append(m.rows, reviewRow{setup: setup})
+
+		tcs := SortedTcs(tcBySetup[setup])
+		for i := range tcs {
+			tc := &tcs[i]
+			m.rows = append(m.rows, reviewRow{setup: setup, tc: tc})
+			m.tcsBySetup[setup] = append(m.tcsBySetup[setup], tc)
+			// By default everything found is selected
+			m.selected[tc.info.id] = true
+		}
+	}
+	return m
+}
+
+func (m reviewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		case "w":
+			m.confirmed = true
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.rows)-1 {
+				m.cursor++
+			}
+		case "enter", "right", "left", "l", "h":
+			if len(m.rows) == 0 {
+				return m, nil
+			}
+			if tc := m.rows[m.cursor].tc; tc != nil {
+				m.expanded[tc.info.id] = !m.expanded[tc.info.id]
+			}
+		case " ", "x":
+			if len(m.rows) == 0 {
+				return m, nil
+			}
+			m.toggle(m.rows[m.cursor])
+		case "a":
+			m.selectAll(true)
+		case "n":
+			m.selectAll(false)
+		}
+	}
+	return m, nil
+}
+
+// toggle Toggles selection of a TC or, for setup header rows, all TCs of the setup
+func (m reviewModel) toggle(row reviewRow) {
+	if row.tc != nil {
+		m.selected[row.tc.info.id] = !m.selected[row.tc.info.id]
+		return
+	}
+
+	allSelected := true
+	for _, tc := range m.tcsBySetup[row.setup] {
+		allSelected = allSelected && m.selected[tc.info.id]
+	}
+	for _, tc := range m.tcsBySetup[row.setup] {
+		m.selected[tc.info.id] = !allSelected
+	}
+}
+
+func (m reviewModel) selectAll(selected bool) {
+	for id := range m.selected {
+		m.selected[id] = selected
+	}
+}
+
+func (m reviewModel) numSelected(tcs []*TestCase) int {
+	num := 0
+	for _, tc := range tcs {
+		if m.selected[tc.info.id] {
+			num++
+		}
+	}
+	return num
+}
+
+func (m reviewModel) View() string {
+	lines := []string{}
+	cursorLine := 0
+
+	for i, row := range m.rows {
+		pointer := "  "
+		if i == m.cursor {
+			pointer = "> "
+			cursorLine = len(lines)
+		}
+
+		if row.tc == nil {
+			tcs := m.tcsBySetup[row.setup]
+			header := fmt.Sprintf("%s (%d/%d)", row.setup, m.numSelected(tcs), len(tcs))
+			lines = append(lines, pointer+ImportantStyle.Render(header))
+			continue
+		}
+
+		tc := row.tc
+		checkbox := "[ ]"
+		if m.selected[tc.info.id] {
+			checkbox = "[x]"
+		}
+		tcTxt := fmt.Sprintf("%s %s (%s) %s", checkbox, tc.info.id, tc.info.estimate, tc.ScriptPath())
+		if i == m.cursor {
+			tcTxt = CursorStyle.Render(tcTxt)
+		}
+		lines = append(lines, pointer+"  "+tcTxt)
+
+		if !m.expanded[tc.info.id] {
+			continue
+		}
+
+		lines = append(lines, "        "+InfoStyle.Render("Chain: ")+formatChain(tc.chain))
+		for _, match := range tc.matches {
+			for _, matchLine := range strings.Split(match.String(), "\n") {
+				lines = append(lines, "        "+strings.TrimRight(matchLine, " \t"))
+			}
+		}
+	}
+
+	// Only show the part of the list around the cursor
+	// that fits on the screen (leave space for the help line)
+	visible := m.height - 2
+	if visible > 0 && len(lines) > visible {
+		start := cursorLine - visible/2
+		if start < 0 {
+			start = 0
+		}
+		if start+visible > len(lines) {
+			start = len(lines) - visible
+		}
+		lines = lines[start : start+visible]
+	}
+
+	return strings.Join(lines, "\n") + "\n\n" + InfoStyle.Render(reviewHelpTxt)
+}
+
+// ReviewTestCases Starts an interactive terminal UI where the user can browse the
+// found TCs (grouped by setup) and select which ones to keep. Returns the
+// selected TCs and whether the user chose to write them.
+func ReviewTestCases(testCases TestCasesMap) (TestCasesMap, bool, error) {
+	m := newReviewModel(testCases)
+
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).StartReturningModel()
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := final.(reviewModel)
+	selected := TestCasesMap{}
+	for id, tc := range testCases {
+		if result.selected[id] {
+			selected[id] = tc
+		}
+	}
+	return selected, result.confirmed, nil
+}
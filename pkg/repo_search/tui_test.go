@@ -0,0 +1,81 @@
+package repo_search
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func keyMsg(key string) tea.KeyMsg {
+	switch key {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "left":
+		return tea.KeyMsg{Type: tea.KeyLeft}
+	case "right":
+		return tea.KeyMsg{Type: tea.KeyRight}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+func TestReviewModelWithoutTcs(t *testing.T) {
+	var model tea.Model = newReviewModel(TestCasesMap{})
+
+	for _, key := range []string{"enter", "right", "left", "l", "h", " ", "x", "up", "down", "j", "k", "a", "n"} {
+		var cmd tea.Cmd
+		model, cmd = model.Update(keyMsg(key))
+		if cmd != nil {
+			t.Errorf("key %q: unexpected command", key)
+		}
+	}
+	model, _ = model.Update(tea.WindowSizeMsg{Height: 10})
+
+	if !strings.Contains(model.View(), reviewHelpTxt) {
+		t.Error("expected the help line in the view")
+	}
+	if m := model.(reviewModel); m.cursor != 0 || m.confirmed {
+		t.Errorf("unexpected model state: cursor %d, confirmed %v", m.cursor, m.confirmed)
+	}
+}
+
+func TestReviewModelSelection(t *testing.T) {
+	testCases := TestCasesMap{
+		"4AP2-1001": {info: TestCaseInfo{id: "4AP2-1001", setup: "BenchA"}},
+		"4AP2-1002": {info: TestCaseInfo{id: "4AP2-1002", setup: "BenchA"}},
+		"4AP2-1003": {info: TestCaseInfo{id: "4AP2-1003", setup: "BenchB"}},
+	}
+	var model tea.Model = newReviewModel(testCases)
+
+	steps := []struct {
+		key      string
+		selected map[string]bool
+	}{
+		// Cursor starts on the header of BenchA -> toggles all of its TCs
+		{" ", map[string]bool{"4AP2-1001": false, "4AP2-1002": false, "4AP2-1003": true}},
+		{"j", map[string]bool{"4AP2-1001": false, "4AP2-1002": false, "4AP2-1003": true}},
+		{"x", map[string]bool{"4AP2-1001": true, "4AP2-1002": false, "4AP2-1003": true}},
+		{"n", map[string]bool{"4AP2-1001": false, "4AP2-1002": false, "4AP2-1003": false}},
+		{"a", map[string]bool{"4AP2-1001": true, "4AP2-1002": true, "4AP2-1003": true}},
+	}
+	for _, step := range steps {
+		model, _ = model.Update(keyMsg(step.key))
+		selected := model.(reviewModel).selected
+		for id, want := range step.selected {
+			if selected[id] != want {
+				t.Errorf("after %q: %s selected = %v, want %v", step.key, id, selected[id], want)
+			}
+		}
+	}
+
+	model, cmd := model.Update(keyMsg("w"))
+	if !model.(reviewModel).confirmed || cmd == nil {
+		t.Error("expected w to confirm and quit")
+	}
+}